@@ -14,3 +14,50 @@ We truncate at 63 chars because some Kubernetes name fields are limited to this
 {{- $name := default .Chart.Name .Values.nameOverride -}}
 {{- printf "%s-%s" .Release.Name $name | trunc 63 | trimSuffix "-" -}}
 {{- end -}}
+
+{{/*
+The rules for the watched resources, granted by the Role or, to watch other namespaces, the ClusterRole of the app.
+*/}}
+{{- define "rules" -}}
+- apiGroups:
+  - jenkins.io
+  resources:
+  - pipelineactivities
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - jenkins.io
+  resources:
+  - facts
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+  - create
+  - delete
+{{- end -}}
+
+{{/*
+The rules which are always granted by the Role of the app, restricted to the release namespace.
+*/}}
+{{- define "namespacedRules" -}}
+- apiGroups:
+  - coordination.k8s.io
+  resources:
+  - leases
+  verbs:
+  - get
+  - create
+  - update
+- apiGroups:
+  - ""
+  resources:
+  - namespaces
+  - secrets
+  verbs:
+  - get
+  - list
+{{- end -}}
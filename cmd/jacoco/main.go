@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/api"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/cluster"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/logging"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/metrics"
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
 	"github.com/jenkins-x/jx/pkg/jx/cmd/clients"
 	log "github.com/sirupsen/logrus"
 	"net/http"
@@ -41,6 +45,16 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	kubeClient, _, err := factory.CreateKubeClient()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	eventHandler, err := cluster.NewEventHandler(jxClient, config)
+	if err != nil {
+		logger.Fatalf("error creating event handler: %s", err)
+	}
+
 	var wg sync.WaitGroup
 	done := make(chan struct{})
 
@@ -54,14 +68,13 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		eventHandler, err := cluster.NewEventHandler(jxClient, config)
-		if err != nil {
-			logger.Errorf("error creating event handler: %s", err)
-			done <- struct{}{}
-			return
+		if config.LeaderElection() {
+			logger.Info("starting event handler for pipelineactivites once elected as leader")
+			cluster.RunAsLeader(kubeClient, config, eventHandler.Start, done)
+		} else {
+			logger.Info("starting event handler for pipelineactivites")
+			eventHandler.Start(done)
 		}
-		logger.Info("starting event handler for pipelineactivites")
-		eventHandler.Start(done)
 
 		logger.Info("event handler has shut down")
 		return
@@ -70,7 +83,7 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		startHTTPServer(done)
+		startHTTPServer(done, eventHandler, jxClient, config)
 		logger.Info("HTTP server has shut down")
 		return
 	}()
@@ -79,11 +92,15 @@ func main() {
 	logger.Info("jacoco has successfully shut down")
 }
 
-func startHTTPServer(done chan struct{}) {
+func startHTTPServer(done chan struct{}, eventHandler cluster.EventHandler, jxClient versioned.Interface, config config.Configuration) {
 	server := &http.Server{Addr: ":8080"}
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 	})
+	http.Handle("/metrics", metrics.Handler())
+	http.HandleFunc("/healthz", probeHandler(eventHandler.Alive))
+	http.HandleFunc("/readyz", probeHandler(eventHandler.Ready))
+	http.Handle(api.Prefix, api.NewServer(jxClient.JenkinsV1(), config).Handler())
 
 	go func() {
 		// returns ErrServerClosed on graceful close
@@ -101,6 +118,19 @@ func startHTTPServer(done chan struct{}) {
 	return
 }
 
+// probeHandler returns a handler for Kubernetes probes, responding with status 200 if the specified check passes and
+// with status 503 and the error of the check otherwise.
+func probeHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			logger.Warnf("%s failed: %s", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
+
 // setupSignalChannel registers a listener for Unix signals for a ordered shutdown
 func setupSignalChannel(done chan struct{}) {
 	sigChan := make(chan os.Signal, 1)
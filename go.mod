@@ -12,10 +12,12 @@ require (
 	github.com/jenkins-x/jx v1.3.1069
 	github.com/magiconair/properties v1.8.0
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v0.9.1
 	github.com/rifflock/lfshook v0.0.0-20180920164130-b9218ef580f5 // indirect
 	github.com/sirupsen/logrus v1.2.0
 	github.com/spf13/viper v1.3.1 // indirect
 	github.com/stretchr/testify v1.3.0
+	gocloud.dev v0.9.0
 	golang.org/x/lint v0.0.0-20190313153728-d0100b6bd8b3 // indirect
 	k8s.io/api v0.0.0-20190126160303-ccdd560a045f
 	k8s.io/apiextensions-apiserver v0.0.0-20181128195303-1f84094d7e8e
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/cluster"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	badgePath = Prefix + "badge/"
+
+	// defaultCounter is the counter type shown by badges and trends unless specified otherwise.
+	defaultCounter = "line"
+
+	unknownValue = "unknown"
+	unknownColor = "#9f9f9f"
+)
+
+var (
+	// kindPreference is the order in which the coverage kinds are shown by a badge if no kind is specified. The merged
+	// kind combines all others.
+	kindPreference = []string{"merged", "unit", "integration", "e2e"}
+
+	badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{html .Label}}: {{html .Value}}">
+<title>{{html .Label}}: {{html .Value}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.ValueWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Label}}</text><text x="{{.LabelX}}" y="14">{{html .Label}}</text>
+<text x="{{.ValueX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Value}}</text><text x="{{.ValueX}}" y="14">{{html .Value}}</text>
+</g>
+</svg>
+`))
+)
+
+// badge is a flat badge with a grey label on the left and a coloured value on the right.
+type badge struct {
+	Label      string
+	Value      string
+	Color      string
+	LabelWidth int
+	ValueWidth int
+}
+
+// Width returns the width of the badge in pixels.
+func (b badge) Width() int {
+	return b.LabelWidth + b.ValueWidth
+}
+
+// LabelX returns the horizontal centre of the label.
+func (b badge) LabelX() int {
+	return b.LabelWidth / 2
+}
+
+// ValueX returns the horizontal centre of the value.
+func (b badge) ValueX() int {
+	return b.LabelWidth + b.ValueWidth/2
+}
+
+// newBadge creates a badge, estimating the width of the texts from the average character width of Verdana.
+func newBadge(label string, value string, color string) badge {
+	return badge{
+		Label:      label,
+		Value:      value,
+		Color:      color,
+		LabelWidth: textWidth(label),
+		ValueWidth: textWidth(value),
+	}
+}
+
+func textWidth(s string) int {
+	return int(math.Ceil(float64(len([]rune(s)))*6.5)) + 10
+}
+
+// coverageBadge serves GET /api/v1/badge/{owner}/{repository}?branch=&counter=&kind=, rendering the coverage of a
+// counter type of the latest build of a branch as SVG badge. The branch defaults to the base branch and the counter
+// type to lines. If no coverage is found, an unknown badge is rendered.
+func (s *Server) coverageBadge(w http.ResponseWriter, r *http.Request) {
+	owner, repository, ok := repositoryPath(r.URL.Path, badgePath)
+	if !ok {
+		writeError(w, http.StatusNotFound, "expected path %s{owner}/{repository}", badgePath)
+		return
+	}
+	query := cluster.CoverageQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     r.URL.Query().Get("branch"),
+	}
+	if query.Branch == "" {
+		query.Branch = s.config.BaseBranch()
+	}
+	counter := r.URL.Query().Get("counter")
+	if counter == "" {
+		counter = defaultCounter
+	}
+	kind := r.URL.Query().Get("kind")
+	label := "coverage"
+	if kind != "" {
+		label = kind + " coverage"
+	}
+
+	maxAge := s.config.BadgeMaxAge()
+	facts, err := s.latestBuildFacts(query, kind)
+	if err != nil {
+		logger.Errorf("unable to list coverage facts of %s/%s: %s", owner, repository, err)
+		maxAge = 0
+	}
+
+	value, color := unknownValue, unknownColor
+	if covered, total, ok := counterCoverage(preferredKindFacts(facts), counter); ok {
+		p := percent(covered, total)
+		value = strconv.FormatFloat(math.Floor(p*10)/10, 'f', -1, 64) + "%"
+		color = s.badgeColor(p)
+	}
+	writeBadge(w, r, newBadge(label, value, color), maxAge)
+}
+
+// preferredKindFacts returns the Facts of the first kind of kindPreference found in the specified Facts, or of the
+// first kind in alphabetical order if none of them is found. Facts of several modules of the same kind are all
+// returned.
+func preferredKindFacts(facts []cluster.CoverageFact) []cluster.CoverageFact {
+	kinds := map[string][]cluster.CoverageFact{}
+	first := ""
+	for _, fact := range facts {
+		kinds[fact.Kind] = append(kinds[fact.Kind], fact)
+		if first == "" || fact.Kind < first {
+			first = fact.Kind
+		}
+	}
+	for _, kind := range kindPreference {
+		if kindFacts, ok := kinds[kind]; ok {
+			return kindFacts
+		}
+	}
+	return kinds[first]
+}
+
+// counterCoverage returns the covered and total items of the specified counter type summed over the specified Facts.
+// It returns false if none of the Facts contains the counter type.
+func counterCoverage(facts []cluster.CoverageFact, counter string) (int, int, bool) {
+	covered, total, found := 0, 0, false
+	for _, fact := range facts {
+		for _, c := range fact.Coverage {
+			if isCounter(c.Type, counter) {
+				covered += c.Covered
+				total += c.Covered + c.Missed
+				found = true
+			}
+		}
+	}
+	return covered, total, found
+}
+
+// isCounter returns true if the specified counter type of a Fact, eg Lines or Branches, is the specified counter, which
+// is matched ignoring case and may be singular, eg line or branch.
+func isCounter(counterType string, counter string) bool {
+	counterType, counter = strings.ToLower(counterType), strings.ToLower(counter)
+	return counterType == counter || strings.TrimSuffix(counterType, "s") == counter || strings.TrimSuffix(counterType, "es") == counter
+}
+
+// badgeColor returns the colour of the first badge threshold reached by the specified percentage, or the colour of the
+// lowest threshold if none is reached.
+func (s *Server) badgeColor(percent float64) string {
+	thresholds := s.config.BadgeThresholds()
+	for _, threshold := range thresholds {
+		if percent >= threshold.Percent {
+			return threshold.Color
+		}
+	}
+	if len(thresholds) == 0 {
+		return unknownColor
+	}
+	return thresholds[len(thresholds)-1].Color
+}
+
+// writeBadge writes the specified badge as SVG response which may be cached for maxAge seconds. The response has an
+// ETag, so that clients can revalidate a cached badge.
+func writeBadge(w http.ResponseWriter, r *http.Request, b badge, maxAge int) {
+	var svg bytes.Buffer
+	if err := badgeTemplate.Execute(&svg, b); err != nil {
+		logger.Errorf("unable to render badge: %s", err)
+		writeError(w, http.StatusInternalServerError, "unable to render badge: %s", err)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(svg.Bytes()))
+	w.Header().Set("ETag", etag)
+	if maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml;charset=utf-8")
+	if _, err := w.Write(svg.Bytes()); err != nil {
+		logger.Warnf("unable to write response: %s", err)
+	}
+}
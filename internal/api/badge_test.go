@@ -0,0 +1,82 @@
+package api
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/cluster"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getBadge(url string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	testServer().Handler().ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCoverageBadge(t *testing.T) {
+	recorder := getBadge("/api/v1/badge/foo/bar", nil)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "image/svg+xml;charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, recorder.Header().Get("ETag"))
+	assert.Contains(t, recorder.Body.String(), "<title>coverage: 25%</title>")
+	assert.Contains(t, recorder.Body.String(), `fill="#e05d44"`)
+}
+
+func TestCoverageBadgeOfKind(t *testing.T) {
+	recorder := getBadge("/api/v1/badge/foo/bar?kind=unit&counter=lines", nil)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<title>unit coverage: 66.6%</title>")
+	assert.Contains(t, recorder.Body.String(), `fill="#dfb317"`)
+}
+
+func TestCoverageBadgeNotModified(t *testing.T) {
+	etag := getBadge("/api/v1/badge/foo/bar?branch=feature", nil).Header().Get("ETag")
+
+	recorder := getBadge("/api/v1/badge/foo/bar?branch=feature", http.Header{"If-None-Match": []string{etag}})
+
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestUnknownCoverageBadge(t *testing.T) {
+	for _, url := range []string{"/api/v1/badge/foo/unknown", "/api/v1/badge/foo/bar?counter=method"} {
+		recorder := getBadge(url, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, url)
+		assert.Contains(t, recorder.Body.String(), "<title>coverage: unknown</title>", url)
+		assert.Contains(t, recorder.Body.String(), `fill="#9f9f9f"`, url)
+	}
+}
+
+func TestCoverageBadgeEscapesLabel(t *testing.T) {
+	recorder := getBadge("/api/v1/badge/foo/bar?kind=%3Cscript%3E", nil)
+
+	assert.Contains(t, recorder.Body.String(), "&lt;script&gt; coverage: unknown")
+	assert.NotContains(t, recorder.Body.String(), "<script>")
+}
+
+func TestPreferredKindFacts(t *testing.T) {
+	facts, err := testServer().latestBuildFacts(cluster.CoverageQuery{Owner: "foo", Repository: "bar", Branch: "master", Build: "10"}, "")
+	assert.NoError(t, err)
+
+	preferred := preferredKindFacts(facts)
+
+	assert.Len(t, preferred, 1)
+	assert.Equal(t, "unit", preferred[0].Kind)
+}
+
+func TestIsCounter(t *testing.T) {
+	assert.True(t, isCounter("Lines", "line"))
+	assert.True(t, isCounter("Lines", "LINES"))
+	assert.True(t, isCounter("Branches", "branch"))
+	assert.True(t, isCounter("Complexity", "complexity"))
+	assert.False(t, isCounter("Lines", "instruction"))
+}
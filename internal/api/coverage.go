@@ -0,0 +1,150 @@
+package api
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/cluster"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	coveragePath = Prefix + "coverage/"
+)
+
+// CoverageResponse is the coverage of a build of a repository branch.
+type CoverageResponse struct {
+	Owner      string         `json:"owner"`
+	Repository string         `json:"repository"`
+	Branch     string         `json:"branch"`
+	Build      int            `json:"build"`
+	Facts      []FactCoverage `json:"facts"`
+}
+
+// FactCoverage is the coverage stored in a single Fact, eg the coverage of one kind of tests.
+type FactCoverage struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Activity  string    `json:"activity"`
+	Kind      string    `json:"kind,omitempty"`
+	Module    string    `json:"module,omitempty"`
+	ReportURL string    `json:"reportUrl"`
+	Created   time.Time `json:"created"`
+	Counters  []Counter `json:"counters"`
+}
+
+// Counter is the coverage of a counter type.
+type Counter struct {
+	Type    string  `json:"type"`
+	Covered int     `json:"covered"`
+	Missed  int     `json:"missed"`
+	Total   int     `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+// coverage serves GET /api/v1/coverage/{owner}/{repository}?branch=&build=&kind=, returning the coverage of the
+// specified build, or of the latest build, of a branch. The branch defaults to the base branch.
+func (s *Server) coverage(w http.ResponseWriter, r *http.Request) {
+	owner, repository, ok := repositoryPath(r.URL.Path, coveragePath)
+	if !ok {
+		writeError(w, http.StatusNotFound, "expected path %s{owner}/{repository}", coveragePath)
+		return
+	}
+	query := cluster.CoverageQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     r.URL.Query().Get("branch"),
+		Build:      r.URL.Query().Get("build"),
+	}
+	if query.Branch == "" {
+		query.Branch = s.config.BaseBranch()
+	}
+
+	facts, err := s.latestBuildFacts(query, r.URL.Query().Get("kind"))
+	if err != nil {
+		logger.Errorf("unable to list coverage facts of %s/%s: %s", owner, repository, err)
+		writeError(w, http.StatusInternalServerError, "unable to list coverage facts: %s", err)
+		return
+	}
+	if len(facts) == 0 {
+		writeError(w, http.StatusNotFound, "no coverage found for %s/%s on branch %s", owner, repository, query.Branch)
+		return
+	}
+
+	response := CoverageResponse{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     query.Branch,
+		Build:      facts[0].Build,
+		Facts:      make([]FactCoverage, 0, len(facts)),
+	}
+	for _, fact := range facts {
+		response.Facts = append(response.Facts, FactCoverage{
+			Name:      fact.Fact.Name,
+			Namespace: fact.Fact.Namespace,
+			Activity:  fact.Fact.Spec.SubjectReference.Name,
+			Kind:      fact.Kind,
+			Module:    fact.Module,
+			ReportURL: fact.Fact.Spec.Original.URL,
+			Created:   fact.Fact.CreationTimestamp.Time,
+			Counters:  counters(fact.Coverage),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// latestBuildFacts returns the coverage Facts matching the query and, if not empty, the specified kind. If the query
+// does not specify a build, only the Facts of the latest build are returned.
+func (s *Server) latestBuildFacts(query cluster.CoverageQuery, kind string) ([]cluster.CoverageFact, error) {
+	facts, err := cluster.ListCoverageFacts(s.facts, s.config, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var kindFacts []cluster.CoverageFact
+	latest := 0
+	for _, fact := range facts {
+		if kind != "" && fact.Kind != kind {
+			continue
+		}
+		kindFacts = append(kindFacts, fact)
+		if fact.Build > latest {
+			latest = fact.Build
+		}
+	}
+	var selected []cluster.CoverageFact
+	for _, fact := range kindFacts {
+		if query.Build == "" && fact.Build != latest {
+			continue
+		}
+		selected = append(selected, fact)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Fact.Name < selected[j].Fact.Name
+	})
+	return selected, nil
+}
+
+// counters converts the coverage of a Fact to counters with lower case type names, eg lines.
+func counters(coverage []cluster.Coverage) []Counter {
+	counters := make([]Counter, 0, len(coverage))
+	for _, c := range coverage {
+		counters = append(counters, Counter{
+			Type:    strings.ToLower(c.Type),
+			Covered: c.Covered,
+			Missed:  c.Missed,
+			Total:   c.Covered + c.Missed,
+			Percent: percent(c.Covered, c.Covered+c.Missed),
+		})
+	}
+	return counters
+}
+
+// percent returns the coverage percentage rounded to two decimal places. A counter without any items is 100% covered.
+func percent(covered int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(float64(covered)*10000/float64(total)) / 100
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsclientv1 "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testConfig is a Configuration with the settings used by the API, all other settings panic.
+type testConfig struct {
+	config.Configuration
+}
+
+func (c *testConfig) Namespace() string {
+	return "jx"
+}
+
+func (c *testConfig) WatchNamespaces() []string {
+	return []string{c.Namespace()}
+}
+
+func (c *testConfig) BaseBranch() string {
+	return "master"
+}
+
+func (c *testConfig) BadgeThresholds() []config.BadgeThreshold {
+	thresholds, _ := config.ParseBadgeThresholds(config.DefaultBadgeThresholds)
+	return thresholds
+}
+
+func (c *testConfig) BadgeMaxAge() int {
+	return 300
+}
+
+// listingFactInterface is a FactInterface listing a fixed set of Facts, all other operations panic.
+type listingFactInterface struct {
+	jenkinsclientv1.FactInterface
+	facts []jenkinsv1.Fact
+}
+
+func (m *listingFactInterface) Facts(namespace string) jenkinsclientv1.FactInterface {
+	return m
+}
+
+func (m *listingFactInterface) List(opts meta_v1.ListOptions) (*jenkinsv1.FactList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &jenkinsv1.FactList{}
+	for _, fact := range m.facts {
+		if selector.Matches(labels.Set(fact.Labels)) {
+			list.Items = append(list.Items, fact)
+		}
+	}
+	return list, nil
+}
+
+func coverageFact(name string, branch string, build string, kind string, covered int, missed int) jenkinsv1.Fact {
+	return jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: "jx",
+			Labels: map[string]string{
+				"subjectkind":  "PipelineActivity",
+				"owner":        "foo",
+				"repository":   "bar",
+				"branch":       branch,
+				"build":        build,
+				"coverageKind": kind,
+			},
+		},
+		Spec: jenkinsv1.FactSpec{
+			FactType:         jenkinsv1.FactTypeCoverage,
+			Tags:             []string{"jacoco"},
+			SubjectReference: jenkinsv1.ResourceReference{Name: "foo-bar-" + branch + "-" + build},
+			Measurements: []jenkinsv1.Measurement{
+				{Name: "Lines-Covered", MeasurementValue: covered},
+				{Name: "Lines-Missed", MeasurementValue: missed},
+				{Name: "Lines-Total", MeasurementValue: covered + missed},
+			},
+		},
+	}
+}
+
+func testServer() *Server {
+	return NewServer(&listingFactInterface{facts: []jenkinsv1.Fact{
+		coverageFact("master-1-unit", "master", "1", "unit", 1, 1),
+		coverageFact("master-10-unit", "master", "10", "unit", 2, 1),
+		coverageFact("master-10-integration", "master", "10", "integration", 0, 0),
+		coverageFact("master-11-e2e", "master", "11", "e2e", 1, 3),
+		coverageFact("feature-12-unit", "feature", "12", "unit", 1, 0),
+	}}, &testConfig{})
+}
+
+func getCoverage(t *testing.T, url string) (int, CoverageResponse) {
+	recorder := httptest.NewRecorder()
+	testServer().Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	var response CoverageResponse
+	if recorder.Code == http.StatusOK {
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	}
+	return recorder.Code, response
+}
+
+func TestCoverageOfLatestBuild(t *testing.T) {
+	status, response := getCoverage(t, "/api/v1/coverage/foo/bar")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "master", response.Branch)
+	assert.Equal(t, 11, response.Build)
+	assert.Len(t, response.Facts, 1)
+	assert.Equal(t, "e2e", response.Facts[0].Kind)
+	assert.Equal(t, "foo-bar-master-11", response.Facts[0].Activity)
+	assert.Equal(t, []Counter{{Type: "lines", Covered: 1, Missed: 3, Total: 4, Percent: 25}}, response.Facts[0].Counters)
+}
+
+func TestCoverageOfBuild(t *testing.T) {
+	status, response := getCoverage(t, "/api/v1/coverage/foo/bar?branch=master&build=10")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 10, response.Build)
+	assert.Len(t, response.Facts, 2)
+	assert.Equal(t, "master-10-integration", response.Facts[0].Name)
+	assert.Equal(t, []Counter{{Type: "lines", Total: 0, Percent: 100}}, response.Facts[0].Counters)
+	assert.Equal(t, "master-10-unit", response.Facts[1].Name)
+	assert.Equal(t, []Counter{{Type: "lines", Covered: 2, Missed: 1, Total: 3, Percent: 66.67}}, response.Facts[1].Counters)
+}
+
+func TestCoverageOfKind(t *testing.T) {
+	status, response := getCoverage(t, "/api/v1/coverage/foo/bar?kind=unit")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 10, response.Build, "the latest build with unit test coverage should be selected")
+	assert.Len(t, response.Facts, 1)
+	assert.Equal(t, "master-10-unit", response.Facts[0].Name)
+}
+
+func TestCoverageNotFound(t *testing.T) {
+	status, _ := getCoverage(t, "/api/v1/coverage/foo/bar?branch=unknown")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = getCoverage(t, "/api/v1/coverage/foo")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestCoverageMethodNotAllowed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	testServer().Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/coverage/foo/bar", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
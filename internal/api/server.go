@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/logging"
+	jenkinsv1types "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Prefix is the path prefix of all API endpoints.
+	Prefix = "/api/v1/"
+)
+
+var (
+	logger = logging.AppLogger().WithFields(log.Fields{"component": "api"})
+)
+
+// Server serves the HTTP API over the stored coverage Facts.
+type Server struct {
+	facts  jenkinsv1types.FactsGetter
+	config config.Configuration
+}
+
+// errorResponse is the body of a failed API request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a new API server reading the coverage Facts via the specified client.
+func NewServer(facts jenkinsv1types.FactsGetter, config config.Configuration) *Server {
+	return &Server{facts: facts, config: config}
+}
+
+// Handler returns the handler of all API endpoints below Prefix.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(coveragePath, get(s.coverage))
+	mux.HandleFunc(badgePath, get(s.coverageBadge))
+	mux.HandleFunc(trendPath, get(s.trend))
+	return mux
+}
+
+// get restricts the specified handler to GET requests.
+func get(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// repositoryPath returns the owner and repository of a request path of the form <prefix><owner>/<repository>.
+// It returns false if the path has another form.
+func repositoryPath(path string, prefix string) (string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// writeJSON writes the specified value as JSON response with the specified status.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Warnf("unable to write response: %s", err)
+	}
+}
+
+// writeError writes an error response with the specified status and formatted message.
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, errorResponse{Error: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/csv"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/cluster"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	trendPath = Prefix + "trend/"
+
+	formatJSON = "json"
+	formatCSV  = "csv"
+
+	// dateLayout is the layout of dates without time in date ranges.
+	dateLayout = "2006-01-02"
+)
+
+var (
+	csvHeader = []string{"build", "activity", "created", "kind", "covered", "missed", "total", "percent"}
+)
+
+// TrendResponse is the coverage of a counter type of the builds of a repository branch.
+type TrendResponse struct {
+	Owner      string       `json:"owner"`
+	Repository string       `json:"repository"`
+	Branch     string       `json:"branch"`
+	Counter    string       `json:"counter"`
+	Points     []TrendPoint `json:"points"`
+}
+
+// TrendPoint is the coverage of a counter type of a single build.
+type TrendPoint struct {
+	Build    int       `json:"build"`
+	Activity string    `json:"activity"`
+	Created  time.Time `json:"created"`
+	Kind     string    `json:"kind,omitempty"`
+	Covered  int       `json:"covered"`
+	Missed   int       `json:"missed"`
+	Total    int       `json:"total"`
+	Percent  float64   `json:"percent"`
+}
+
+// trend serves GET /api/v1/trend/{owner}/{repository}?branch=&counter=&kind=&from=&to=&limit=&format=, returning the
+// coverage of a counter type of each build of a branch in ascending order of the builds, either as JSON or as CSV.
+// The branch defaults to the base branch and the counter type to lines.
+func (s *Server) trend(w http.ResponseWriter, r *http.Request) {
+	owner, repository, ok := repositoryPath(r.URL.Path, trendPath)
+	if !ok {
+		writeError(w, http.StatusNotFound, "expected path %s{owner}/{repository}", trendPath)
+		return
+	}
+	params := r.URL.Query()
+	query := cluster.CoverageQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     params.Get("branch"),
+	}
+	if query.Branch == "" {
+		query.Branch = s.config.BaseBranch()
+	}
+	counter := strings.ToLower(params.Get("counter"))
+	if counter == "" {
+		counter = defaultCounter
+	}
+
+	from, err := parseTime(params.Get("from"), false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from '%s', expected a date like 2019-03-01 or an RFC 3339 time", params.Get("from"))
+		return
+	}
+	to, err := parseTime(params.Get("to"), true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to '%s', expected a date like 2019-03-31 or an RFC 3339 time", params.Get("to"))
+		return
+	}
+	limit := 0
+	if value := params.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "invalid limit '%s', expected a positive number", value)
+			return
+		}
+	}
+	format, ok := responseFormat(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid format '%s', expected %s or %s", params.Get("format"), formatJSON, formatCSV)
+		return
+	}
+
+	facts, err := cluster.ListCoverageFacts(s.facts, s.config, query)
+	if err != nil {
+		logger.Errorf("unable to list coverage facts of %s/%s: %s", owner, repository, err)
+		writeError(w, http.StatusInternalServerError, "unable to list coverage facts: %s", err)
+		return
+	}
+
+	points := trendPoints(facts, params.Get("kind"), counter)
+	points = filterPoints(points, from, to, limit)
+
+	if format == formatCSV {
+		writeCSV(w, points)
+		return
+	}
+	writeJSON(w, http.StatusOK, TrendResponse{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     query.Branch,
+		Counter:    counter,
+		Points:     points,
+	})
+}
+
+// trendPoints returns a point per build with the coverage of the specified counter type, in ascending order of the
+// build numbers and, for equal build numbers, the creation times. If no kind is specified, all points are of the kind
+// the badge of the latest build shows, so that the series does not switch between kinds. Builds without the kind or
+// the counter type are omitted.
+func trendPoints(facts []cluster.CoverageFact, kind string, counter string) []TrendPoint {
+	if kind == "" {
+		kind = seriesKind(facts)
+	}
+	builds := map[string][]cluster.CoverageFact{}
+	for _, fact := range facts {
+		if kind != "" && fact.Kind != kind {
+			continue
+		}
+		activity := fact.Fact.Namespace + "/" + fact.Fact.Spec.SubjectReference.Name
+		builds[activity] = append(builds[activity], fact)
+	}
+
+	points := make([]TrendPoint, 0, len(builds))
+	for _, buildFacts := range builds {
+		covered, total, ok := counterCoverage(buildFacts, counter)
+		if !ok {
+			continue
+		}
+		point := TrendPoint{
+			Build:    buildFacts[0].Build,
+			Activity: buildFacts[0].Fact.Spec.SubjectReference.Name,
+			Kind:     buildFacts[0].Kind,
+			Covered:  covered,
+			Missed:   total - covered,
+			Total:    total,
+			Percent:  percent(covered, total),
+		}
+		for _, fact := range buildFacts {
+			if created := fact.Fact.CreationTimestamp.Time; created.After(point.Created) {
+				point.Created = created
+			}
+		}
+		points = append(points, point)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Build != points[j].Build {
+			return points[i].Build < points[j].Build
+		}
+		return points[i].Created.Before(points[j].Created)
+	})
+	return points
+}
+
+// seriesKind returns the kind of the trend series of the specified Facts, the kind selected like for badges from the
+// Facts of the latest build.
+func seriesKind(facts []cluster.CoverageFact) string {
+	latest := 0
+	for _, fact := range facts {
+		if fact.Build > latest {
+			latest = fact.Build
+		}
+	}
+	var latestFacts []cluster.CoverageFact
+	for _, fact := range facts {
+		if fact.Build == latest {
+			latestFacts = append(latestFacts, fact)
+		}
+	}
+	if len(latestFacts) == 0 {
+		return ""
+	}
+	return preferredKindFacts(latestFacts)[0].Kind
+}
+
+// filterPoints returns the points created in the range [from, to), where a zero time leaves the range open, limited
+// to the specified number of latest points. A limit of 0 returns all points in the range.
+func filterPoints(points []TrendPoint, from time.Time, to time.Time, limit int) []TrendPoint {
+	filtered := make([]TrendPoint, 0, len(points))
+	for _, point := range points {
+		if !from.IsZero() && point.Created.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !point.Created.Before(to) {
+			continue
+		}
+		filtered = append(filtered, point)
+	}
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered
+}
+
+// parseTime parses an RFC 3339 time or a date. If end is true, a date is parsed as the start of the following day, so
+// that the range includes the whole day. An empty string is parsed as zero time.
+func parseTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// responseFormat returns the format of the response, taken from the format query parameter or, if missing, from the
+// Accept header. It returns false if the format query parameter is invalid.
+func responseFormat(r *http.Request) (string, bool) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case formatJSON, formatCSV:
+		return format, true
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			return formatCSV, true
+		}
+		return formatJSON, true
+	default:
+		return "", false
+	}
+}
+
+// writeCSV writes the specified points as CSV response with a header line.
+func writeCSV(w http.ResponseWriter, points []TrendPoint) {
+	w.Header().Set("Content-Type", "text/csv;charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	records := [][]string{csvHeader}
+	for _, point := range points {
+		records = append(records, []string{
+			strconv.Itoa(point.Build),
+			point.Activity,
+			point.Created.UTC().Format(time.RFC3339),
+			point.Kind,
+			strconv.Itoa(point.Covered),
+			strconv.Itoa(point.Missed),
+			strconv.Itoa(point.Total),
+			strconv.FormatFloat(point.Percent, 'f', -1, 64),
+		})
+	}
+	if err := writer.WriteAll(records); err != nil {
+		logger.Warnf("unable to write response: %s", err)
+	}
+}
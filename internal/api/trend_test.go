@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/cluster"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func trendFact(name string, build string, kind string, created string, covered int, missed int) jenkinsv1.Fact {
+	fact := coverageFact(name, "master", build, kind, covered, missed)
+	timestamp, _ := time.Parse(time.RFC3339, created)
+	fact.CreationTimestamp = meta_v1.NewTime(timestamp)
+	return fact
+}
+
+func getTrend(url string, header http.Header) *httptest.ResponseRecorder {
+	server := NewServer(&listingFactInterface{facts: []jenkinsv1.Fact{
+		trendFact("master-10-unit", "10", "unit", "2019-03-10T08:00:00Z", 6, 4),
+		trendFact("master-10-merged", "10", "merged", "2019-03-10T08:01:00Z", 7, 3),
+		trendFact("master-2-unit", "2", "unit", "2019-03-02T08:00:00Z", 1, 3),
+		trendFact("master-3-unit", "3", "unit", "2019-03-03T08:00:00Z", 1, 1),
+		trendFact("master-3-integration", "3", "integration", "2019-03-03T08:00:00Z", 0, 2),
+		trendFact("master-11-unit", "11", "unit", "2019-03-11T23:00:00Z", 3, 1),
+	}}, &testConfig{})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	server.Handler().ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestTrend(t *testing.T) {
+	recorder := getTrend("/api/v1/trend/foo/bar", nil)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response TrendResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "master", response.Branch)
+	assert.Equal(t, "line", response.Counter)
+	assert.Len(t, response.Points, 4)
+
+	var builds []int
+	for _, point := range response.Points {
+		builds = append(builds, point.Build)
+	}
+	assert.Equal(t, []int{2, 3, 10, 11}, builds, "points should be ordered by build number")
+	assert.Equal(t, TrendPoint{
+		Build:    10,
+		Activity: "foo-bar-master-10",
+		Created:  time.Date(2019, 3, 10, 8, 0, 0, 0, time.UTC),
+		Kind:     "unit",
+		Covered:  6,
+		Missed:   4,
+		Total:    10,
+		Percent:  60,
+	}, response.Points[2], "all points should be of the kind of the latest build")
+	assert.Equal(t, "unit", response.Points[1].Kind)
+}
+
+func TestTrendPointsOfPreferredKind(t *testing.T) {
+	coverageFact := func(build int, kind string, covered int, missed int) cluster.CoverageFact {
+		fact := &jenkinsv1.Fact{Spec: jenkinsv1.FactSpec{SubjectReference: jenkinsv1.ResourceReference{Name: fmt.Sprintf("foo-bar-master-%d", build)}}}
+		return cluster.CoverageFact{Fact: fact, Build: build, Kind: kind, Coverage: []cluster.Coverage{{Type: "LINE", Covered: covered, Missed: missed}}}
+	}
+	facts := []cluster.CoverageFact{
+		coverageFact(1, "unit", 5, 5),
+		coverageFact(2, "unit", 6, 4),
+		coverageFact(2, "integration", 2, 8),
+		coverageFact(2, "merged", 7, 3),
+		coverageFact(3, "unit", 7, 3),
+		coverageFact(3, "merged", 8, 2),
+	}
+
+	points := trendPoints(facts, "", "line")
+
+	assert.Len(t, points, 2, "builds without the merged coverage should be omitted")
+	for _, point := range points {
+		assert.Equal(t, "merged", point.Kind)
+	}
+	assert.Equal(t, 2, points[0].Build)
+	assert.Equal(t, 7, points[0].Covered)
+	assert.Equal(t, 3, points[1].Build)
+	assert.Equal(t, 8, points[1].Covered)
+}
+
+func TestTrendOfKind(t *testing.T) {
+	recorder := getTrend("/api/v1/trend/foo/bar?kind=integration", nil)
+
+	var response TrendResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Points, 1)
+	assert.Equal(t, 3, response.Points[0].Build)
+	assert.Equal(t, float64(0), response.Points[0].Percent)
+}
+
+func TestTrendRangeAndLimit(t *testing.T) {
+	var tests = []struct {
+		url    string
+		builds []int
+	}{
+		{"/api/v1/trend/foo/bar?from=2019-03-03", []int{3, 10, 11}},
+		{"/api/v1/trend/foo/bar?to=2019-03-11", []int{2, 3, 10, 11}},
+		{"/api/v1/trend/foo/bar?from=2019-03-03&to=2019-03-10", []int{3, 10}},
+		{"/api/v1/trend/foo/bar?to=2019-03-10T08:00:00Z", []int{2, 3}},
+		{"/api/v1/trend/foo/bar?limit=2", []int{10, 11}},
+		{"/api/v1/trend/foo/bar?from=2019-03-01&to=2019-03-10&limit=1", []int{10}},
+		{"/api/v1/trend/foo/bar?from=2019-04-01", []int{}},
+		{"/api/v1/trend/foo/bar?counter=method", []int{}},
+	}
+
+	for _, test := range tests {
+		recorder := getTrend(test.url, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, test.url)
+		var response TrendResponse
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response), test.url)
+		builds := []int{}
+		for _, point := range response.Points {
+			builds = append(builds, point.Build)
+		}
+		assert.Equal(t, test.builds, builds, test.url)
+	}
+}
+
+func TestTrendInvalidParameters(t *testing.T) {
+	for _, url := range []string{
+		"/api/v1/trend/foo/bar?from=yesterday",
+		"/api/v1/trend/foo/bar?to=2019-13-01",
+		"/api/v1/trend/foo/bar?limit=0",
+		"/api/v1/trend/foo/bar?limit=ten",
+		"/api/v1/trend/foo/bar?format=xml",
+	} {
+		assert.Equal(t, http.StatusBadRequest, getTrend(url, nil).Code, url)
+	}
+}
+
+func TestTrendCSV(t *testing.T) {
+	expected := "build,activity,created,kind,covered,missed,total,percent\n" +
+		"10,foo-bar-master-10,2019-03-10T08:00:00Z,unit,6,4,10,60\n" +
+		"11,foo-bar-master-11,2019-03-11T23:00:00Z,unit,3,1,4,75\n"
+
+	for _, recorder := range []*httptest.ResponseRecorder{
+		getTrend("/api/v1/trend/foo/bar?format=csv&limit=2", nil),
+		getTrend("/api/v1/trend/foo/bar?limit=2", http.Header{"Accept": []string{"text/csv"}}),
+	} {
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/csv;charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, expected, recorder.Body.String())
+	}
+}
@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"strconv"
+)
+
+const (
+	packageTag = "package"
+	classTag   = "class"
+
+	// breakdownOmittedAnnotation is the annotation of a Fact containing the number of breakdown measurements which
+	// were omitted to keep the Fact within the configured maximum size.
+	breakdownOmittedAnnotation = "jacoco.jenkins-x.io/breakdown-omitted"
+)
+
+// addBreakdown adds the measurements of the packages and, depending on the configured breakdown, the classes of the
+// specified report to the Fact. The names of the measurements are prefixed with the package or class name, eg
+// org/example/Lines-Covered, and the measurements are tagged with package or class respectively.
+//
+// The measurements are added as long as the serialized Fact stays within the configured maximum size, packages before
+// classes. The number of omitted measurements is recorded in an annotation of the Fact. The size check is approximate,
+// annotations added afterwards are not accounted for, so the maximum size needs to leave some headroom to the etcd limit.
+func (h *defaultEventHandler) addBreakdown(fact *jenkinsv1.Fact, coverageReport report.Report) {
+	breakdown := h.config.Breakdown()
+	if breakdown != config.BreakdownPackage && breakdown != config.BreakdownClass {
+		return
+	}
+
+	packages := coverageReport.AllPackages()
+	var measurements []jenkinsv1.Measurement
+	for _, pkg := range packages {
+		measurements = append(measurements, h.prefixedMeasurements(pkg.Name, packageTag, pkg.Counters)...)
+	}
+	if breakdown == config.BreakdownClass {
+		for _, pkg := range packages {
+			for _, class := range pkg.Classes {
+				measurements = append(measurements, h.prefixedMeasurements(class.Name, classTag, class.Counters)...)
+			}
+		}
+	}
+
+	size := jsonSize(fact)
+	maxSize := h.config.MaxFactSize()
+	for i, measurement := range measurements {
+		// each measurement adds its own JSON plus a separating comma
+		size += jsonSize(measurement) + 1
+		if size > maxSize {
+			omitted := len(measurements) - i
+			logger.Warnf("omitting %d of %d breakdown measurements of fact '%s' to stay within %d bytes", omitted, len(measurements), fact.Name, maxSize)
+			if fact.Annotations == nil {
+				fact.Annotations = map[string]string{}
+			}
+			fact.Annotations[breakdownOmittedAnnotation] = strconv.Itoa(omitted)
+			return
+		}
+		fact.Spec.Measurements = append(fact.Spec.Measurements, measurement)
+	}
+}
+
+// prefixedMeasurements creates the measurements for the specified counters, prefixing their names with the specified
+// prefix and tagging them with the specified tag.
+func (h *defaultEventHandler) prefixedMeasurements(prefix string, tag string, counters []report.Counter) []jenkinsv1.Measurement {
+	measurements := h.createMeasurements(counters)
+	for i := range measurements {
+		measurements[i].Name = fmt.Sprintf("%s/%s", prefix, measurements[i].Name)
+		measurements[i].Tags = []string{tag}
+	}
+	return measurements
+}
+
+// jsonSize returns the size of the specified object serialized as JSON.
+func jsonSize(obj interface{}) int {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var breakdownReport = report.Report{
+	Counters: []report.Counter{{Type: "LINE", Missed: 10, Covered: 30}},
+	Packages: []report.Package{
+		{
+			Name:     "org/example/a",
+			Counters: []report.Counter{{Type: "LINE", Missed: 10, Covered: 10}},
+			Classes: []report.Class{
+				{Name: "org/example/a/Foo", Counters: []report.Counter{{Type: "LINE", Missed: 10, Covered: 10}}},
+			},
+		},
+	},
+	Groups: []report.Group{
+		{
+			Name: "group",
+			Packages: []report.Package{
+				{Name: "org/example/b", Counters: []report.Counter{{Type: "LINE", Missed: 0, Covered: 20}}},
+			},
+		},
+	},
+}
+
+func TestCreateFactWithoutBreakdown(t *testing.T) {
+	handler := defaultEventHandler{config: &testConfig{breakdown: config.BreakdownNone, maxFactSize: 1000000}}
+	fact := handler.createAggregatedFact([]report.Report{breakdownReport}, []string{"http://dummy/jacoco.xml"}, getFakePipelineActivity(t), "", kindUnit)
+
+	assert.Len(t, fact.Spec.Measurements, 4)
+}
+
+func TestCreateFactWithPackageBreakdown(t *testing.T) {
+	handler := defaultEventHandler{config: &testConfig{breakdown: config.BreakdownPackage, maxFactSize: 1000000}}
+	fact := handler.createAggregatedFact([]report.Report{breakdownReport}, []string{"http://dummy/jacoco.xml"}, getFakePipelineActivity(t), "", kindUnit)
+
+	assert.Len(t, fact.Spec.Measurements, 12)
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "org/example/a/Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10, Tags: []string{packageTag}})
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "org/example/b/Lines-Percent", MeasurementType: jenkinsv1.MeasurementPercent, MeasurementValue: 100, Tags: []string{packageTag}})
+	assert.Empty(t, fact.Annotations[breakdownOmittedAnnotation])
+}
+
+func TestCreateFactWithClassBreakdown(t *testing.T) {
+	handler := defaultEventHandler{config: &testConfig{breakdown: config.BreakdownClass, maxFactSize: 1000000}}
+	fact := handler.createAggregatedFact([]report.Report{breakdownReport}, []string{"http://dummy/jacoco.xml"}, getFakePipelineActivity(t), "", kindUnit)
+
+	assert.Len(t, fact.Spec.Measurements, 16)
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "org/example/a/Foo/Lines-Missed", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10, Tags: []string{classTag}})
+}
+
+func TestBreakdownIsCappedByFactSize(t *testing.T) {
+	activity := getFakePipelineActivity(t)
+	handler := defaultEventHandler{config: &testConfig{breakdown: config.BreakdownNone}}
+	fact := handler.createAggregatedFact([]report.Report{breakdownReport}, []string{"http://dummy/jacoco.xml"}, activity, "", kindUnit)
+
+	// leave room for the four measurements of a single package
+	measurement := jenkinsv1.Measurement{Name: "org/example/a/Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10, Tags: []string{packageTag}}
+	maxSize := jsonSize(fact) + 4*(jsonSize(measurement)+1)
+
+	handler = defaultEventHandler{config: &testConfig{breakdown: config.BreakdownClass, maxFactSize: maxSize}}
+	fact = handler.createAggregatedFact([]report.Report{breakdownReport}, []string{"http://dummy/jacoco.xml"}, activity, "", kindUnit)
+
+	assert.Len(t, fact.Spec.Measurements, 8)
+	assert.Contains(t, fact.Spec.Measurements, measurement)
+	assert.NotContains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "org/example/a/Foo/Lines-Missed", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10, Tags: []string{classTag}})
+	assert.Equal(t, "8", fact.Annotations[breakdownOmittedAnnotation])
+}
+
+func TestAggregatedTotalsDoNotDependOnBreakdown(t *testing.T) {
+	module := func(name string, missed int, covered int) report.Report {
+		counters := []report.Counter{{Type: "LINE", Missed: missed, Covered: covered}}
+		return report.Report{
+			Name:     name,
+			Counters: counters,
+			Packages: []report.Package{{
+				Name:        "com/acme",
+				SourceFiles: []report.SourceFile{{Name: "Application.java", Counters: counters}},
+				Counters:    counters,
+			}},
+		}
+	}
+	reports := []report.Report{module("module-a", 1, 3), module("module-b", 4, 2)}
+	urls := []string{"http://dummy/module-a/target/site/jacoco/jacoco.xml", "http://dummy/module-b/target/site/jacoco/jacoco.xml"}
+	// without breakdown only the report counters are decoded
+	var totalsOnly []report.Report
+	for _, r := range reports {
+		totalsOnly = append(totalsOnly, report.Report{Name: r.Name, Counters: r.Counters})
+	}
+
+	withoutBreakdown := defaultEventHandler{config: &testConfig{breakdown: config.BreakdownNone, maxFactSize: 1000000}}
+	withBreakdown := defaultEventHandler{config: &testConfig{breakdown: config.BreakdownPackage, maxFactSize: 1000000}}
+	fact := withoutBreakdown.createAggregatedFact(totalsOnly, urls, getFakePipelineActivity(t), "", kindUnit)
+	breakdownFact := withBreakdown.createAggregatedFact(reports, urls, getFakePipelineActivity(t), "", kindUnit)
+
+	assert.Equal(t, []Coverage{{Type: "Lines", Covered: 5, Missed: 5}}, FactCoverage(fact))
+	assert.Equal(t, FactCoverage(fact), FactCoverage(breakdownFact))
+	assert.Contains(t, breakdownFact.Spec.Measurements, jenkinsv1.Measurement{Name: "com/acme/Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 5, Tags: []string{packageTag}})
+}
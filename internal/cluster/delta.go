@@ -0,0 +1,167 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// deltaMeasurement is the suffix of the names of coverage delta measurements, eg Lines-Delta.
+	deltaMeasurement = "Delta"
+
+	// deltaStatementType is the statement type of the coverage delta statements.
+	deltaStatementType = "coverage-delta"
+
+	deltaTag = "delta"
+)
+
+var (
+	pullRequestBranch = regexp.MustCompile(`(?i)^pr-\d+$`)
+)
+
+// addDelta adds the change of the coverage compared to the most recent previous build to the Fact. For each counter
+// type a delta measurement, eg Lines-Delta, and a statement describing the change, eg "Lines coverage went from 81.2%
+// to 79.8%", is added. The statement is true if the coverage did not decrease.
+//
+// The previous build is the most recent build of the same repository and branch or, for pull requests, of the
+// configured base branch which has a coverage Fact. The base branch is the same for all repositories, the actual
+// target branch of a pull request is not known. If there is no such build, no delta is added.
+func (h *defaultEventHandler) addDelta(fact *jenkinsv1.Fact, coverageReport report.Report, pipelineActivity *jenkinsv1.PipelineActivity) {
+	previous := h.previousFact(pipelineActivity, fact)
+	if previous == nil {
+		return
+	}
+
+	precision := h.config.PercentPrecision()
+	for _, c := range coverageReport.Counters {
+		t := measurementCountType(c.Type)
+		previousCovered, ok := measurementValue(previous, fmt.Sprintf("%s-%s", t, jenkinsv1.CodeCoverageMeasurementCoverage))
+		if !ok {
+			continue
+		}
+		previousTotal, ok := measurementValue(previous, fmt.Sprintf("%s-%s", t, jenkinsv1.CodeCoverageMeasurementTotal))
+		if !ok {
+			continue
+		}
+
+		total := c.Covered + c.Missed
+		delta := scaledPercent(c.Covered, total, precision) - scaledPercent(previousCovered, previousTotal, precision)
+		fact.Spec.Measurements = append(fact.Spec.Measurements, jenkinsv1.Measurement{
+			Name:             fmt.Sprintf("%s-%s", t, deltaMeasurement),
+			MeasurementType:  jenkinsv1.MeasurementPercent,
+			MeasurementValue: delta,
+			Tags:             []string{deltaTag},
+		})
+		fact.Spec.Statements = append(fact.Spec.Statements, jenkinsv1.Statement{
+			Name:             fmt.Sprintf("%s coverage went from %s to %s", t, formatPercent(previousCovered, previousTotal), formatPercent(c.Covered, total)),
+			StatementType:    deltaStatementType,
+			MeasurementValue: delta >= 0,
+			Tags: []string{
+				deltaTag,
+				fmt.Sprintf("previous=%s", previous.Spec.SubjectReference.Name),
+			},
+		})
+	}
+}
+
+// previousFact returns the coverage Fact of the most recent previous build of the specified activity corresponding to
+// the specified Fact, ie the Fact of the same module and coverage kind with the same tags. The Facts are selected by the
+// owner, repository and branch labels and listed in pages of factPageSize Facts, the most recent build is the one with
+// the highest build label. For a pull request the builds of the base branch are considered, otherwise the builds of
+// the same branch with a lower build number.
+func (h *defaultEventHandler) previousFact(pipelineActivity *jenkinsv1.PipelineActivity, fact *jenkinsv1.Fact) *jenkinsv1.Fact {
+	spec := pipelineActivity.Spec
+	branch := spec.GitBranch
+	sameBranch := !pullRequestBranch.MatchString(branch)
+	if !sameBranch {
+		branch = h.config.BaseBranch()
+	}
+	build, err := strconv.Atoi(spec.Build)
+	if sameBranch && err != nil {
+		return nil
+	}
+
+	selector := []string{fmt.Sprintf("%s=%s", subjectKindLabel, activityKind)}
+	for label, value := range map[string]string{
+		ownerLabel:      spec.GitOwner,
+		repositoryLabel: spec.GitRepository,
+		branchLabel:     branch,
+	} {
+		v := labelValue(value)
+		if v == "" {
+			return nil
+		}
+		selector = append(selector, fmt.Sprintf("%s=%s", label, v))
+	}
+
+	var previous *jenkinsv1.Fact
+	previousBuild := 0
+	factsInterface := h.facts.Facts(pipelineActivity.Namespace)
+	options := metav1.ListOptions{LabelSelector: strings.Join(selector, ","), Limit: factPageSize}
+	for {
+		facts, err := factsInterface.List(options)
+		if err != nil {
+			logger.Warnf("unable to retrieve coverage of previous builds of '%s': %s", pipelineActivity.Name, err)
+			return nil
+		}
+		for i := range facts.Items {
+			candidate := &facts.Items[i]
+			if candidate.Spec.SubjectReference.UID == pipelineActivity.UID || !sameFactKind(candidate, fact) {
+				continue
+			}
+			b, err := strconv.Atoi(candidate.Labels[buildLabel])
+			if err != nil || (sameBranch && b >= build) {
+				continue
+			}
+			if previous == nil || b > previousBuild {
+				previous, previousBuild = candidate, b
+			}
+		}
+		if facts.Continue == "" {
+			return previous
+		}
+		options.Continue = facts.Continue
+	}
+}
+
+// sameFactKind returns true if the specified Facts are of the same module and coverage kind and have the same tags,
+// eg the tag of the attachment they were created from.
+func sameFactKind(a *jenkinsv1.Fact, b *jenkinsv1.Fact) bool {
+	if a.Labels[moduleTag] != b.Labels[moduleTag] || a.Labels[coverageKindLabel] != b.Labels[coverageKindLabel] {
+		return false
+	}
+	return reflect.DeepEqual(tagSet(a.Spec.Tags), tagSet(b.Spec.Tags))
+}
+
+// tagSet returns the set of the specified tags.
+func tagSet(tags []string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	return set
+}
+
+// measurementValue returns the value of the measurement with the specified name.
+func measurementValue(fact *jenkinsv1.Fact, name string) (int, bool) {
+	for _, measurement := range fact.Spec.Measurements {
+		if measurement.Name == name {
+			return measurement.MeasurementValue, true
+		}
+	}
+	return 0, false
+}
+
+// formatPercent formats the coverage percentage with a single decimal place, eg 81.2%.
+func formatPercent(covered int, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(covered)*100/float64(total))
+}
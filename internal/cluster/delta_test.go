@@ -0,0 +1,159 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func activity(name string, branch string, build string) *jenkinsv1.PipelineActivity {
+	return &jenkinsv1.PipelineActivity{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "jx", Name: name, UID: types.UID(name)},
+		Spec: jenkinsv1.PipelineActivitySpec{
+			GitOwner:      "foo",
+			GitRepository: "bar",
+			GitBranch:     branch,
+			Build:         build,
+		},
+	}
+}
+
+// linesFact returns the unit test coverage Fact of the specified activity with the specified line coverage.
+func linesFact(a *jenkinsv1.PipelineActivity, covered int, total int) jenkinsv1.Fact {
+	labels := factLabels(a)
+	labels[coverageKindLabel] = kindUnit
+	return jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{Name: factName(a.Name), Namespace: a.Namespace, Labels: labels},
+		Spec: jenkinsv1.FactSpec{
+			Tags:             []string{appName, kindUnit},
+			SubjectReference: jenkinsv1.ResourceReference{Name: a.Name, UID: a.UID},
+			Measurements: []jenkinsv1.Measurement{
+				{Name: "Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: covered},
+				{Name: "Lines-Total", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: total},
+			},
+		},
+	}
+}
+
+func deltaHandler(facts ...jenkinsv1.Fact) defaultEventHandler {
+	return defaultEventHandler{
+		config: &testConfig{baseBranch: "master", percentPrecision: 1},
+		facts:  &listingFactInterface{facts: facts},
+	}
+}
+
+func TestPreviousFact(t *testing.T) {
+	current := activity("foo-bar-master-3", "master", "3")
+	handler := deltaHandler(
+		linesFact(current, 1, 1),
+		linesFact(activity("foo-bar-master-1", "master", "1"), 1, 1),
+		linesFact(activity("foo-bar-master-2", "master", "2"), 1, 1),
+		linesFact(activity("foo-bar-master-4", "master", "4"), 1, 1),
+		linesFact(activity("foo-bar-feature-2", "feature", "2"), 1, 1),
+		linesFact(activity("foo-baz-master-2", "master", "2"), 1, 1),
+	)
+
+	fact := linesFact(current, 1, 1)
+	previous := handler.previousFact(current, &fact)
+	assert.Equal(t, factName("foo-bar-master-2"), previous.Name)
+}
+
+func TestPreviousFactOfPullRequest(t *testing.T) {
+	current := activity("foo-bar-pr-6-1", "PR-6", "1")
+	handler := deltaHandler(
+		linesFact(activity("foo-bar-master-1", "master", "1"), 1, 1),
+		linesFact(activity("foo-bar-master-7", "master", "7"), 1, 1),
+		linesFact(activity("foo-bar-pr-6-0", "PR-6", "0"), 1, 1),
+	)
+
+	fact := linesFact(current, 1, 1)
+	previous := handler.previousFact(current, &fact)
+	assert.Equal(t, factName("foo-bar-master-7"), previous.Name)
+}
+
+// moduleFact returns the coverage Fact of the specified module, with a name truncated like the names of long module
+// Facts.
+func moduleFact(a *jenkinsv1.PipelineActivity, module string) jenkinsv1.Fact {
+	fact := linesFact(a, 1, 1)
+	fact.Name = truncateName(fmt.Sprintf("%s-%s-%s", fact.Name, strings.Repeat("x", maxNameLength), module))
+	fact.Labels[moduleTag] = module
+	fact.Spec.Tags = append(fact.Spec.Tags, moduleTag)
+	return fact
+}
+
+func TestPreviousFactOfModule(t *testing.T) {
+	current := activity("foo-bar-master-3", "master", "3")
+	previous := activity("foo-bar-master-2", "master", "2")
+	handler := deltaHandler(
+		linesFact(previous, 1, 1),
+		moduleFact(previous, "core"),
+		moduleFact(activity("foo-bar-master-1", "master", "1"), "core"),
+	)
+
+	core := moduleFact(current, "core")
+	assert.Equal(t, moduleFact(previous, "core").Name, handler.previousFact(current, &core).Name)
+	api := moduleFact(current, "api")
+	assert.Nil(t, handler.previousFact(current, &api))
+}
+
+func TestPreviousFactOfKind(t *testing.T) {
+	current := activity("foo-bar-master-3", "master", "3")
+	integration := linesFact(activity("foo-bar-master-1", "master", "1"), 1, 1)
+	kindFact(&integration, kindIntegration)
+	tagged := linesFact(activity("foo-bar-master-2", "master", "2"), 1, 1)
+	tagFact(&tagged, "web")
+	handler := deltaHandler(linesFact(activity("foo-bar-master-2", "master", "2"), 1, 1), integration, tagged)
+
+	fact := linesFact(current, 1, 1)
+	kindFact(&fact, kindIntegration)
+	assert.Equal(t, integration.Name, handler.previousFact(current, &fact).Name)
+	fact = linesFact(current, 1, 1)
+	tagFact(&fact, "web")
+	assert.Equal(t, tagged.Name, handler.previousFact(current, &fact).Name)
+}
+
+func TestPreviousFactPages(t *testing.T) {
+	current := activity("foo-bar-master-999", "master", "999")
+	var facts []jenkinsv1.Fact
+	for i := 1; i <= factPageSize*2+1; i++ {
+		facts = append(facts, linesFact(activity(fmt.Sprintf("foo-bar-master-%d", i), "master", strconv.Itoa(i)), 1, 1))
+	}
+	handler := deltaHandler(facts...)
+
+	fact := linesFact(current, 1, 1)
+	previous := handler.previousFact(current, &fact)
+	assert.Equal(t, factName(fmt.Sprintf("foo-bar-master-%d", factPageSize*2+1)), previous.Name)
+	assert.Equal(t, 3, handler.facts.(*listingFactInterface).listCount)
+}
+
+func TestAddDelta(t *testing.T) {
+	current := activity("foo-bar-master-3", "master", "3")
+	// build 2 has no coverage Fact, so build 1 is compared against
+	handler := deltaHandler(linesFact(activity("foo-bar-master-1", "master", "1"), 812, 1000))
+
+	fact := handler.createAggregatedFact([]report.Report{{Counters: []report.Counter{{Type: "LINE", Missed: 202, Covered: 798}}}}, []string{"http://dummy/jacoco.xml"}, current, "", kindUnit)
+
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Delta", MeasurementType: jenkinsv1.MeasurementPercent, MeasurementValue: -14, Tags: []string{deltaTag}})
+	assert.Contains(t, fact.Spec.Statements, jenkinsv1.Statement{
+		Name:             "Lines coverage went from 81.2% to 79.8%",
+		StatementType:    deltaStatementType,
+		MeasurementValue: false,
+		Tags:             []string{deltaTag, "previous=foo-bar-master-1"},
+	})
+}
+
+func TestAddDeltaWithoutPreviousBuild(t *testing.T) {
+	current := activity("foo-bar-master-1", "master", "1")
+	handler := deltaHandler()
+
+	fact := handler.createAggregatedFact([]report.Report{{Counters: []report.Counter{{Type: "LINE", Missed: 1, Covered: 1}}}}, []string{"http://dummy/jacoco.xml"}, current, "", kindUnit)
+
+	assert.Len(t, fact.Spec.Measurements, 4)
+	assert.Empty(t, fact.Spec.Statements)
+}
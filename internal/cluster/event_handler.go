@@ -3,7 +3,9 @@ package cluster
 import (
 	"fmt"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/gate"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/logging"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/metrics"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
 	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
@@ -14,6 +16,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"reflect"
+	"sync"
 	"time"
 )
 
@@ -22,6 +27,10 @@ const (
 	SyncPeriod = time.Minute * 10
 	resource   = "pipelineactivities"
 	appName    = "jacoco"
+	moduleTag  = "module"
+
+	// percentMeasurement is the suffix of the names of coverage percentage measurements, eg Lines-Percent.
+	percentMeasurement = "Percent"
 )
 
 var (
@@ -41,37 +50,62 @@ type EventHandler interface {
 
 	// Start starts the event handler passing it a done channel.
 	Start(done chan struct{})
+
+	// Ready returns an error if the event handler is not ready to process pipeline activities.
+	Ready() error
+
+	// Alive returns an error if the event handler stopped making progress.
+	Alive() error
 }
 
 type defaultEventHandler struct {
-	jxClient jenkinsv1client.Interface
-	config   config.JXConfig
+	jxClient  jenkinsv1client.Interface
+	facts     jenkinsv1types.FactsGetter
+	config    config.Configuration
+	processed *processedCache
+	queue     workqueue.RateLimitingInterface
+	// indexers maps the watched namespaces to the indexers of their pipeline activities
+	indexers map[string]cache.Indexer
+	health   health
 }
 
 // NewEventHandler creates a new event handler using the JX REST client.
 // A instance of defaultEventHandler handles syncing of a single CRD type specified via crdType.
-func NewEventHandler(jxClient jenkinsv1client.Interface, config config.JXConfig) (EventHandler, error) {
-	return &defaultEventHandler{jxClient: jxClient, config: config}, nil
+func NewEventHandler(jxClient jenkinsv1client.Interface, config config.Configuration) (EventHandler, error) {
+	return &defaultEventHandler{
+		jxClient:  jxClient,
+		facts:     jxClient.JenkinsV1(),
+		config:    config,
+		processed: newProcessedCache(),
+		queue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), resource),
+	}, nil
 }
 
 func (h *defaultEventHandler) Add(obj interface{}) {
-	h.onPipelineActivity(obj)
+	h.enqueue(obj)
 }
 
 func (h *defaultEventHandler) Update(oldObj interface{}, newObj interface{}) {
-	h.onPipelineActivity(newObj)
+	h.enqueue(newObj)
 }
 
 func (h *defaultEventHandler) Delete(obj interface{}) {
-
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pipelineActivity, ok := obj.(*jenkinsv1.PipelineActivity)
+	if !ok {
+		logger.Warnf("unexpected type %T", obj)
+		return
+	}
+	h.processed.forget(pipelineActivity.UID)
+	// the Facts of the deleted activity are cleaned up when its key is processed
+	h.enqueue(pipelineActivity)
 }
 
+// Start runs the informer and the configured number of workers until done is closed. On shutdown the workers finish
+// processing the activities which are already queued before Start returns.
 func (h *defaultEventHandler) Start(done chan struct{}) {
-	listWatch := cache.NewListWatchFromClient(h.jxClient.JenkinsV1().RESTClient(),
-		resource,
-		h.config.Namespace(),
-		fields.Everything())
-
 	handlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			h.Add(obj)
@@ -80,100 +114,530 @@ func (h *defaultEventHandler) Start(done chan struct{}) {
 			h.Update(old, new)
 		},
 		DeleteFunc: func(obj interface{}) {
+			h.Delete(obj)
 		},
 	}
-	_, controller := cache.NewInformer(listWatch, nil, SyncPeriod, handlerFuncs)
 
-	controller.Run(done)
+	// the indexers are published once complete, the health probes only read their own snapshot under its lock
+	namespaces := watchNamespaces(h.config)
+	indexers := map[string]cache.Indexer{}
+	var indexerList []cache.Indexer
+	var synced []cache.InformerSynced
+	for _, namespace := range namespaces {
+		count, err := h.processed.seed(h.facts.Facts(namespace))
+		if err != nil {
+			logger.Warnf("unable to seed cache of processed activities from existing facts in %s: %s", describeNamespace(namespace), err)
+		} else {
+			logger.Infof("seeded cache of processed activities from %d existing facts in %s", count, describeNamespace(namespace))
+		}
+
+		listWatch := cache.NewListWatchFromClient(h.jxClient.JenkinsV1().RESTClient(),
+			resource,
+			namespace,
+			fields.Everything())
+
+		indexer, controller := cache.NewIndexerInformer(listWatch, &jenkinsv1.PipelineActivity{}, SyncPeriod, handlerFuncs, cache.Indexers{})
+		indexers[namespace] = indexer
+		indexerList = append(indexerList, indexer)
+		synced = append(synced, controller.HasSynced)
+
+		go controller.Run(done)
+	}
+	h.indexers = indexers
+	h.health.start(synced, indexerList)
+	if !cache.WaitForCacheSync(done, synced...) {
+		logger.Warn("shutdown before the pipeline activity cache was synced")
+		h.queue.ShutDown()
+		return
+	}
+
+	for _, namespace := range namespaces {
+		uids, err := sweepOrphanedFacts(h.facts, namespace, h.indexers[namespace].List())
+		if err != nil {
+			logger.Warnf("unable to delete facts of deleted pipeline activities in %s: %s", describeNamespace(namespace), err)
+		}
+		for _, uid := range uids {
+			h.processed.forget(uid)
+		}
+	}
+
+	workers := h.config.Workers()
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.runWorker()
+		}()
+	}
+
+	<-done
+	logger.Info("shutting down workers")
+	h.queue.ShutDown()
+	wg.Wait()
+}
+
+// enqueue adds the key of the specified pipeline activity to the queue. An activity which is already queued is only
+// processed once.
+func (h *defaultEventHandler) enqueue(obj interface{}) {
+	h.health.event()
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		logger.Warnf("unable to determine key of %T: %s", obj, err)
+		return
+	}
+	h.queue.Add(key)
+}
+
+// runWorker processes queued pipeline activities until the queue is shut down.
+func (h *defaultEventHandler) runWorker() {
+	for h.processNextItem() {
+	}
 }
 
-func (h *defaultEventHandler) onPipelineActivity(obj interface{}) {
+// processNextItem processes the next queued pipeline activity. It returns false once the queue is shut down.
+func (h *defaultEventHandler) processNextItem() bool {
+	key, quit := h.queue.Get()
+	if quit {
+		return false
+	}
+	defer h.queue.Done(key)
+	h.health.processing()
+	defer h.health.processed()
+
+	h.handleErr(h.sync(key.(string)), key)
+	return true
+}
+
+// sync processes the pipeline activity with the specified key in its latest known state.
+func (h *defaultEventHandler) sync(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	indexer := h.indexer(namespace)
+	if indexer == nil {
+		return fmt.Errorf("namespace '%s' is not watched", namespace)
+	}
+	obj, exists, err := indexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		logger.Debugf("pipeline activity '%s' no longer exists, deleting its facts", key)
+		_, err = deleteFacts(h.facts, namespace, name)
+		return err
+	}
+	return h.onPipelineActivity(obj)
+}
+
+// handleErr requeues the key of a failed pipeline activity with backoff, until the configured number of retries is
+// exhausted.
+func (h *defaultEventHandler) handleErr(err error, key interface{}) {
+	if err == nil {
+		h.queue.Forget(key)
+		return
+	}
+
+	if h.queue.NumRequeues(key) < h.config.MaxRetries() {
+		logger.Warnf("error processing pipeline activity '%s', retrying: %s", key, err)
+		h.queue.AddRateLimited(key)
+		return
+	}
+
+	h.queue.Forget(key)
+	logger.Errorf("error processing pipeline activity '%s', giving up: %s", key, err)
+}
+
+// onPipelineActivity processes the coverage attachments of the specified pipeline activity. All attachments are
+// processed, the returned error combines the errors of the failed attachments.
+func (h *defaultEventHandler) onPipelineActivity(obj interface{}) error {
 	pipelineActivity, ok := obj.(*jenkinsv1.PipelineActivity)
 	if !ok {
 		logger.Warnf("unexpected type %T", obj)
-		return
+		return nil
+	}
+
+	status := string(pipelineActivity.Spec.Status)
+	if !util.Contains(h.config.ProcessStatuses(), status) {
+		logger.Debugf("skipping pipeline activity '%s' with status '%s'", pipelineActivity.Name, status)
+		return nil
 	}
 
 	logger.Debugf("processing pipeline activity '%s'", pipelineActivity.Name)
+	var coverageAttachments []jenkinsv1.Attachment
 	for _, attachment := range pipelineActivity.Spec.Attachments {
-		if attachment.Name != appName {
+		if _, ok := h.attachmentConfig(attachment.Name); ok && len(attachment.URLs) > 0 {
+			coverageAttachments = append(coverageAttachments, attachment)
+		}
+	}
+	options := h.decodeOptions(coverageAttachments)
+
+	var errs util.MultiError
+	// reports retrieved for the attachments of this activity, keyed by attachment name
+	retrieved := map[string][]report.Report{}
+	for _, attachment := range pipelineActivity.Spec.Attachments {
+		attachmentConfig, ok := h.attachmentConfig(attachment.Name)
+		if !ok {
+			continue
+		}
+		if h.isProcessed(pipelineActivity, attachment) {
+			logger.Debugf("attachment '%s' of pipeline activity '%s' is unchanged since it was last processed", attachment.Name, pipelineActivity.Name)
 			continue
 		}
 
-		factsInterface := h.jxClient.JenkinsV1().Facts(h.config.Namespace())
-		for _, url := range attachment.URLs {
-			logger.Debugf("processing report '%s'", url)
-			//  append version string to report URL to avoid any caching issues when retrieving the report
-			urlWithTimestamp := fmt.Sprintf("%s?version=%d", url, time.Now().UnixNano()/int64(time.Millisecond))
-			report, err := report.RetrieveReport(h.config.Namespace(), urlWithTimestamp)
-			if err != nil {
-				logger.Errorf("unable to retrieve report from %s: %s", url, err)
-				continue
-			}
+		reports, err := h.retrieveReports(h.config.Namespace(), attachment.URLs, attachmentConfig.Format, options)
+		if err != nil {
+			errs.Collect(fmt.Errorf("unable to retrieve reports of attachment '%s': %s", attachment.Name, err))
+			continue
+		}
+		if len(reports) == 0 {
+			continue
+		}
+		retrieved[attachment.Name] = reports
+
+		facts := h.createKindFacts(reports, attachment, attachmentConfig, pipelineActivity)
+		errs.Collect(h.storeFacts(facts, pipelineActivity, attachment))
+	}
+	errs.Collect(h.storeMergedFact(coverageAttachments, retrieved, pipelineActivity))
+	return errs.ToError()
+}
+
+// isProcessed returns true if the specified attachment of the activity has been processed and does not need to be
+// processed again. If Facts are overwritten, attachments are always processed again, since a report may be replaced
+// at the same URL. Unchanged Facts are not written again.
+func (h *defaultEventHandler) isProcessed(pipelineActivity *jenkinsv1.PipelineActivity, attachment jenkinsv1.Attachment) bool {
+	return !h.config.OverwriteFacts() && h.processed.isProcessed(pipelineActivity.UID, attachment)
+}
+
+// storeFacts stores the Facts created from the specified attachment. The attachment is marked as processed, if all
+// Facts were stored successfully.
+func (h *defaultEventHandler) storeFacts(facts []*jenkinsv1.Fact, pipelineActivity *jenkinsv1.PipelineActivity, attachment jenkinsv1.Attachment) error {
+	var errs util.MultiError
+	factsInterface := h.facts.Facts(pipelineActivity.Namespace)
+	for _, fact := range facts {
+		annotateFact(fact, attachment)
+		err := h.storeFact(fact, factsInterface)
+		if err != nil {
+			errs.Collect(fmt.Errorf("error storing Fact %s: %s", fact.Spec.Name, err))
+		} else {
+			logger.Infof("successfully stored fact '%s' for report from %s", fact.Spec.Name, fact.Spec.Original.URL)
+			recordCoverage(fact)
+		}
+	}
+	if errs.Empty() {
+		h.processed.markProcessed(pipelineActivity.UID, attachment.Name, attachmentFingerprint(attachment))
+	}
+	return errs.ToError()
+}
+
+// storeMergedFact stores a Fact combining the coverage of all kinds of the specified attachments, eg the coverage of
+// unit and integration tests, if the attachments contain more than one kind of coverage. The reports of all
+// attachments are merged so that a line covered by any kind of test counts as covered. Reports which were not
+// retrieved while processing the individual attachments are retrieved again. Without line details the coverage
+// cannot be merged, so no Fact is stored if line details are skipped.
+func (h *defaultEventHandler) storeMergedFact(attachments []jenkinsv1.Attachment, retrieved map[string][]report.Report, pipelineActivity *jenkinsv1.PipelineActivity) error {
+	if !h.config.MergeCoverageKinds() {
+		return nil
+	}
+	if h.config.SkipLineDetail() {
+		logger.Warnf("not merging the coverage kinds of '%s' since line details are skipped", pipelineActivity.Name)
+		return nil
+	}
+
+	merged := jenkinsv1.Attachment{Name: kindMerged}
+	for _, attachment := range attachments {
+		merged.URLs = append(merged.URLs, attachment.URLs...)
+	}
+	if len(h.coverageKinds(attachments)) < 2 || h.isProcessed(pipelineActivity, merged) {
+		return nil
+	}
 
-			fact := h.createFact(report, pipelineActivity, url)
-			err = h.storeFact(fact, factsInterface)
+	var reports []report.Report
+	var urls []string
+	for _, attachment := range attachments {
+		attachmentReports, ok := retrieved[attachment.Name]
+		if !ok {
+			attachmentConfig, _ := h.attachmentConfig(attachment.Name)
+			var err error
+			attachmentReports, err = h.retrieveReports(h.config.Namespace(), attachment.URLs, attachmentConfig.Format, h.decodeOptions(attachments))
 			if err != nil {
-				logger.Errorf("error storing Fact %s: %s", fact.Spec.Name, err)
-			} else {
-				logger.Infof("successfully stored JaCoCo fact '%s' for report from %s", fact.Spec.Name, fact.Spec.Original.URL)
+				return fmt.Errorf("unable to retrieve reports of attachment '%s' for the merged coverage: %s", attachment.Name, err)
 			}
 		}
+		reports = append(reports, attachmentReports...)
+		urls = append(urls, attachment.URLs...)
 	}
+
+	fact := h.createAggregatedFact([]report.Report{mergeKinds(reports, urls)}, merged.URLs, pipelineActivity, kindMerged, kindMerged)
+	return h.storeFacts([]*jenkinsv1.Fact{fact}, pipelineActivity, merged)
+}
+
+// coverageKinds returns the kinds of coverage contained in the reports of the specified attachments.
+func (h *defaultEventHandler) coverageKinds(attachments []jenkinsv1.Attachment) map[string]bool {
+	kinds := map[string]bool{}
+	for _, attachment := range attachments {
+		attachmentConfig, _ := h.attachmentConfig(attachment.Name)
+		for _, group := range kindGroups(attachmentConfig, attachment.URLs) {
+			kinds[group.kind] = true
+		}
+	}
+	return kinds
+}
+
+// decodeOptions returns the details decoded from the streamed reports of the specified coverage attachments of an
+// activity. Packages are only decoded if breakdown measurements, package quality gate rules or a merged Fact of
+// several coverage kinds need them. Otherwise only the report counters are kept, so that the memory usage does not
+// depend on the report size.
+func (h *defaultEventHandler) decodeOptions(attachments []jenkinsv1.Attachment) report.DecodeOptions {
+	options := report.DecodeOptions{SkipLines: h.config.SkipLineDetail()}
+	if h.config.Breakdown() != config.BreakdownNone {
+		return options
+	}
+	rules, _ := gate.ParseRules(h.config.GateRules())
+	for _, rule := range rules {
+		if rule.PerPackage {
+			return options
+		}
+	}
+	if h.config.MergeCoverageKinds() && len(h.coverageKinds(attachments)) > 1 {
+		return options
+	}
+	options.SkipPackages = true
+	return options
 }
 
+// attachmentConfig returns the configuration of the attachment with the specified name. It returns false if
+// attachments with this name are not processed.
+func (h *defaultEventHandler) attachmentConfig(name string) (config.Attachment, bool) {
+	for _, attachment := range h.config.Attachments() {
+		if attachment.Name == name {
+			return attachment, true
+		}
+	}
+	return config.Attachment{}, false
+}
+
+// retrieveReports retrieves the reports from the specified URLs, using the credentials of the specified namespace. The
+// credentials are always taken from the namespace of the app, the only namespace whose secrets the app may read. If a
+// single report cannot be retrieved an error is returned, so that no Fact is created from an incomplete set of reports.
+func (h *defaultEventHandler) retrieveReports(namespace string, urls []string, format report.Format, options report.DecodeOptions) ([]report.Report, error) {
+	reports := make([]report.Report, 0, len(urls))
+	for _, url := range urls {
+		logger.Debugf("processing report '%s'", url)
+		//  append version string to report URL to avoid any caching issues when retrieving the report
+		urlWithTimestamp := fmt.Sprintf("%s?version=%d", url, time.Now().UnixNano()/int64(time.Millisecond))
+		reportFormat := format
+		if reportFormat == report.FormatUnknown {
+			reportFormat = report.FormatForName(url)
+		}
+		r, err := h.retrieveReport(namespace, urlWithTimestamp, reportFormat, options)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve report from %s: %s", url, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// retrieveReport retrieves the report from the specified URL, decoding it as a stream with the specified options if
+// configured.
+func (h *defaultEventHandler) retrieveReport(namespace string, url string, format report.Format, options report.DecodeOptions) (report.Report, error) {
+	if !h.config.StreamReports() {
+		return report.RetrieveFormattedReport(namespace, url, format)
+	}
+	return report.StreamReport(namespace, url, format, options)
+}
+
+// storeFact creates the specified Fact. Creating the Fact is retried with backoff unless the error is permanent, eg
+// the Fact is invalid. If the Fact already exists and Facts are configured to be overwritten, the existing Fact is
+// updated, retrying only updates which conflict with a concurrent modification.
 func (h *defaultEventHandler) storeFact(fact *jenkinsv1.Fact, factsInterface jenkinsv1types.FactInterface) error {
-	f := func() error {
+	exists := false
+	err := util.ApplyWithBackoffIf(func() error {
 		_, err := factsInterface.Create(fact)
-		if err != nil {
-			switch err.(type) {
-			case *errors.StatusError:
-				status := err.(*errors.StatusError)
-				if status.ErrStatus.Reason == metav1.StatusReasonAlreadyExists {
-					logger.Debugf("fact with name '%s' already existed", fact.Name)
-					return nil
-				}
-				return err
-			default:
-				return err
-			}
+		if errors.IsAlreadyExists(err) {
+			exists = true
+			return nil
 		}
+		metrics.ObserveFactWrite(metrics.OperationCreate, err)
+		return err
+	}, isTransient)
+	if err != nil || !exists {
+		return err
+	}
+
+	if !h.config.OverwriteFacts() {
+		logger.Debugf("fact with name '%s' already existed", fact.Name)
 		return nil
 	}
-	return util.ApplyWithBackoff(f)
+	return util.ApplyWithBackoffIf(func() error {
+		return h.updateFact(fact, factsInterface)
+	}, errors.IsConflict)
 }
 
-func (h *defaultEventHandler) createFact(report report.Report, pipelineActivity *jenkinsv1.PipelineActivity, url string) *jenkinsv1.Fact {
-	measurements := make([]jenkinsv1.Measurement, 0)
-	for _, c := range report.Counters {
-		t := ""
-		switch c.Type {
-		case "INSTRUCTION":
-			t = jenkinsv1.CodeCoverageCountTypeInstructions
-		case "LINE":
-			t = jenkinsv1.CodeCoverageCountTypeLines
-		case "METHOD":
-			t = jenkinsv1.CodeCoverageCountTypeMethods
-		case "COMPLEXITY":
-			t = jenkinsv1.CodeCoverageCountTypeComplexity
-		case "BRANCH":
-			t = jenkinsv1.CodeCoverageCountTypeBranches
-		case "CLASS":
-			t = jenkinsv1.CodeCoverageCountTypeClasses
+// isTransient returns false for errors which do not go away by retrying the same request.
+func isTransient(err error) bool {
+	return !errors.IsInvalid(err) &&
+		!errors.IsBadRequest(err) &&
+		!errors.IsForbidden(err) &&
+		!errors.IsUnauthorized(err) &&
+		!errors.IsNotFound(err) &&
+		!errors.IsMethodNotSupported(err)
+}
+
+// updateFact updates the existing Fact with the name of the specified Fact, unless the measurements, statements, the
+// report URL and the labels and annotations set by this app are unchanged. Only the spec and the labels and annotations
+// of the specified Fact are written, other metadata of the existing Fact, eg labels added by other tools, is kept.
+// An update conflicting with a concurrent modification is returned as error, so that the update gets retried against
+// the latest version of the Fact.
+func (h *defaultEventHandler) updateFact(fact *jenkinsv1.Fact, factsInterface jenkinsv1types.FactInterface) error {
+	existing, err := factsInterface.Get(fact.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if existing.Spec.Original.URL == fact.Spec.Original.URL &&
+		reflect.DeepEqual(existing.Spec.Measurements, fact.Spec.Measurements) &&
+		reflect.DeepEqual(existing.Spec.Statements, fact.Spec.Statements) &&
+		containsAll(existing.Labels, fact.Labels) &&
+		containsAll(existing.Annotations, fact.Annotations) {
+		logger.Debugf("fact with name '%s' already existed and is unchanged", fact.Name)
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Spec = fact.Spec
+	updated.Labels = mergeStrings(existing.Labels, fact.Labels)
+	updated.Annotations = mergeStrings(existing.Annotations, fact.Annotations)
+	_, err = factsInterface.Update(updated)
+	metrics.ObserveFactWrite(metrics.OperationUpdate, err)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("updated existing fact with name '%s'", fact.Name)
+	return nil
+}
+
+// containsAll returns true if all entries of values are contained in m.
+func containsAll(m map[string]string, values map[string]string) bool {
+	for key, value := range values {
+		if v, ok := m[key]; !ok || v != value {
+			return false
 		}
-		measurementCovered := h.createMeasurement(t, jenkinsv1.CodeCoverageMeasurementCoverage, c.Covered)
-		measurementMissed := h.createMeasurement(t, jenkinsv1.CodeCoverageMeasurementMissed, c.Missed)
-		measurementTotal := h.createMeasurement(t, jenkinsv1.CodeCoverageMeasurementTotal, c.Covered+c.Missed)
-		measurements = append(measurements, measurementCovered, measurementMissed, measurementTotal)
+	}
+	return true
+}
+
+// mergeStrings returns a copy of m with the entries of values added, replacing entries with the same keys.
+func mergeStrings(m map[string]string, values map[string]string) map[string]string {
+	merged := make(map[string]string, len(m)+len(values))
+	for key, value := range m {
+		merged[key] = value
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	return merged
+}
+
+// tagFact adds the specified attachment tag to the tags of the Fact and appends it to the name of the Fact, so that
+// the Facts of different attachments of an activity do not collide. An empty tag leaves the Fact unchanged.
+func tagFact(fact *jenkinsv1.Fact, tag string) {
+	if tag == "" {
+		return
+	}
+	name := fmt.Sprintf("%s-%s", fact.Name, tag)
+	fact.Name = name
+	fact.Spec.Name = name
+	fact.Spec.Tags = append(fact.Spec.Tags, tag)
+}
+
+// createKindFacts creates the Facts for the reports of an attachment, separately for each kind of coverage the reports
+// contain. If an attachment contains several kinds, the Facts are tagged and named after their kind, so that they do
+// not collide.
+func (h *defaultEventHandler) createKindFacts(reports []report.Report, attachment jenkinsv1.Attachment, attachmentConfig config.Attachment, pipelineActivity *jenkinsv1.PipelineActivity) []*jenkinsv1.Fact {
+	var facts []*jenkinsv1.Fact
+	groups := kindGroups(attachmentConfig, attachment.URLs)
+	for _, group := range groups {
+		tag := attachmentConfig.Tag
+		if len(groups) > 1 {
+			tag = kindTag(tag, group.kind)
+		}
+		facts = append(facts, h.createFacts(group.reports(reports), group.urls(attachment.URLs), pipelineActivity, tag, group.kind)...)
+	}
+	return facts
+}
+
+// createFacts creates the Facts of the specified coverage kind for the reports of an attachment. Depending on the
+// configured Fact mode either a single aggregated Fact or, if the attachment has several reports, a Fact per module is
+// created.
+func (h *defaultEventHandler) createFacts(reports []report.Report, urls []string, pipelineActivity *jenkinsv1.PipelineActivity, tag string, kind string) []*jenkinsv1.Fact {
+	if h.config.FactMode() != config.FactModeModule || len(reports) == 1 {
+		return []*jenkinsv1.Fact{h.createAggregatedFact(reports, urls, pipelineActivity, tag, kind)}
+	}
+
+	facts := make([]*jenkinsv1.Fact, 0, len(reports))
+	for i, module := range moduleIDs(reports, urls) {
+		facts = append(facts, h.createModuleFact(reports[i], module, pipelineActivity, urls[i], tag, kind))
+	}
+	return facts
+}
+
+// createModuleFact creates the Fact for a single module of a multi-module build. The name of the Fact is suffixed with
+// the module identifier and the Fact is labelled with the module, in addition to the labels linking it to the activity.
+// Names exceeding the maximum resource name length are truncated.
+func (h *defaultEventHandler) createModuleFact(coverageReport report.Report, module string, pipelineActivity *jenkinsv1.PipelineActivity, url string, tag string, kind string) *jenkinsv1.Fact {
+	fact := h.createFact(coverageReport, pipelineActivity, url)
+	tagFact(fact, tag)
+	name := truncateName(fmt.Sprintf("%s-%s", fact.Name, module))
+	fact.Name = name
+	fact.Spec.Name = name
+	fact.Labels[moduleTag] = labelValue(module)
+	fact.Spec.Tags = append(fact.Spec.Tags, moduleTag)
+	kindFact(fact, kind)
+	h.addDelta(fact, coverageReport, pipelineActivity)
+	h.addBreakdown(fact, coverageReport)
+	return fact
+}
+
+// createAggregatedFact creates a single Fact for the reports of an attachment. If the attachment has several reports,
+// eg one per module of a multi-module build, the Fact contains the coverage of the combined reports as well as the
+// coverage of each module. The measurements of a module are prefixed with the module identifier and tagged as such.
+func (h *defaultEventHandler) createAggregatedFact(reports []report.Report, urls []string, pipelineActivity *jenkinsv1.PipelineActivity, tag string, kind string) *jenkinsv1.Fact {
+	merged := report.Combine(reports...)
+	fact := h.createFact(merged, pipelineActivity, urls[0])
+	tagFact(fact, tag)
+	kindFact(fact, kind)
+	if len(reports) > 1 {
+		for i, module := range moduleIDs(reports, urls) {
+			fact.Spec.Measurements = append(fact.Spec.Measurements, h.prefixedMeasurements(module, moduleTag, reports[i].Counters)...)
+		}
+	}
+	h.addDelta(fact, merged, pipelineActivity)
+	h.addBreakdown(fact, merged)
+	return fact
+}
+
+func (h *defaultEventHandler) createFact(coverageReport report.Report, pipelineActivity *jenkinsv1.PipelineActivity, url string) *jenkinsv1.Fact {
+	format := coverageReport.Format
+	if format == report.FormatUnknown {
+		format = report.FormatJaCoCo
 	}
 
-	name := fmt.Sprintf("%s-%s-%s", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name)
+	measurements := h.createMeasurements(coverageReport.Counters)
+
+	name := factName(pipelineActivity.Name)
 	fact := jenkinsv1.Fact{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
-			Labels: map[string]string{
-				"subjectkind": "PipelineActivity",
-				"pipelineName": pipelineActivity.Name,
+			Name:      name,
+			Namespace: pipelineActivity.Namespace,
+			Labels:    factLabels(pipelineActivity),
+			OwnerReferences: []metav1.OwnerReference{
+				ownerReference(pipelineActivity),
 			},
 		},
 		Spec: jenkinsv1.FactSpec{
@@ -181,16 +645,16 @@ func (h *defaultEventHandler) createFact(report report.Report, pipelineActivity
 			FactType: jenkinsv1.FactTypeCoverage,
 			Original: jenkinsv1.Original{
 				URL:      url,
-				MimeType: "application/xml",
+				MimeType: format.MimeType(),
 				Tags: []string{
-					"jacoco.xml",
+					format.FileName(),
 				},
 			},
 			Tags: []string{
 				appName,
 			},
 			Measurements: measurements,
-			Statements:   []jenkinsv1.Statement{},
+			Statements:   h.createStatements(coverageReport),
 			SubjectReference: jenkinsv1.ResourceReference{
 				APIVersion: pipelineActivity.APIVersion,
 				Kind:       pipelineActivity.Kind,
@@ -203,6 +667,21 @@ func (h *defaultEventHandler) createFact(report report.Report, pipelineActivity
 	return &fact
 }
 
+// createMeasurements creates the covered, missed and total measurements for the specified counters.
+func (h *defaultEventHandler) createMeasurements(counters []report.Counter) []jenkinsv1.Measurement {
+	measurements := make([]jenkinsv1.Measurement, 0)
+	precision := h.config.PercentPrecision()
+	for _, c := range counters {
+		t := measurementCountType(c.Type)
+		measurementCovered := h.createMeasurement(t, jenkinsv1.CodeCoverageMeasurementCoverage, c.Covered)
+		measurementMissed := h.createMeasurement(t, jenkinsv1.CodeCoverageMeasurementMissed, c.Missed)
+		measurementTotal := h.createMeasurement(t, jenkinsv1.CodeCoverageMeasurementTotal, c.Covered+c.Missed)
+		measurementPercent := h.createPercentMeasurement(t, c.Covered, c.Covered+c.Missed, precision)
+		measurements = append(measurements, measurementCovered, measurementMissed, measurementTotal, measurementPercent)
+	}
+	return measurements
+}
+
 func (h *defaultEventHandler) createMeasurement(t string, measurement string, value int) jenkinsv1.Measurement {
 	return jenkinsv1.Measurement{
 		Name:             fmt.Sprintf("%s-%s", t, measurement),
@@ -210,3 +689,52 @@ func (h *defaultEventHandler) createMeasurement(t string, measurement string, va
 		MeasurementValue: value,
 	}
 }
+
+// createPercentMeasurement creates the coverage percentage measurement of the specified counter type. As measurement
+// values are integers, the percentage is scaled by 10^precision and rounded down, eg 83.456% with a precision of 2 is
+// stored as 8345. Rounding down ensures that only a fully covered counter reaches 100%. A counter without any items
+// has nothing left to cover and is therefore reported as 100% covered.
+func (h *defaultEventHandler) createPercentMeasurement(t string, covered int, total int, precision int) jenkinsv1.Measurement {
+	return jenkinsv1.Measurement{
+		Name:             fmt.Sprintf("%s-%s", t, percentMeasurement),
+		MeasurementType:  jenkinsv1.MeasurementPercent,
+		MeasurementValue: scaledPercent(covered, total, precision),
+	}
+}
+
+// scaledPercent returns the coverage percentage scaled by 10^precision and rounded down.
+func scaledPercent(covered int, total int, precision int) int {
+	scale := int64(100)
+	for i := 0; i < precision; i++ {
+		scale *= 10
+	}
+
+	if total == 0 {
+		return int(scale)
+	}
+	return int(int64(covered) * scale / int64(total))
+}
+
+// measurementCountType returns the measurement name prefix of the specified JaCoCo counter type, eg Lines for LINE.
+func measurementCountType(counterType string) string {
+	switch counterType {
+	case report.CounterTypeInstruction:
+		return jenkinsv1.CodeCoverageCountTypeInstructions
+	case report.CounterTypeLine:
+		return jenkinsv1.CodeCoverageCountTypeLines
+	case report.CounterTypeMethod:
+		return jenkinsv1.CodeCoverageCountTypeMethods
+	case report.CounterTypeComplexity:
+		return jenkinsv1.CodeCoverageCountTypeComplexity
+	case report.CounterTypeBranch:
+		return jenkinsv1.CodeCoverageCountTypeBranches
+	case report.CounterTypeClass:
+		return jenkinsv1.CodeCoverageCountTypeClasses
+	}
+	return ""
+}
+
+// factName returns the name of the coverage Fact of the pipeline activity with the specified name.
+func factName(activityName string) string {
+	return fmt.Sprintf("%s-%s-%s", appName, jenkinsv1.FactTypeCoverage, activityName)
+}
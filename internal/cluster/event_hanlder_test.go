@@ -3,14 +3,18 @@ package cluster
 import (
 	"fmt"
 	"github.com/bxcodec/faker"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
 	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
 	jenkinsclientv1 "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/workqueue"
 	"testing"
 )
 
@@ -18,6 +22,93 @@ var dummyFact = &jenkinsv1.Fact{
 	Spec: jenkinsv1.FactSpec{},
 }
 
+// testConfig is a Configuration for tests. Only the overridden methods can be called.
+type testConfig struct {
+	config.Configuration
+	factMode         string
+	overwriteFacts   bool
+	maxRetries       int
+	percentPrecision int
+	breakdown        string
+	maxFactSize      int
+	gateRules        string
+	baseBranch       string
+	processStatuses  []string
+	attachments      []config.Attachment
+	mergeKinds       bool
+	leaseDuration    int
+	leaderIdentity   string
+	watchNamespaces  []string
+	skipLineDetail   bool
+}
+
+func (c *testConfig) SkipLineDetail() bool {
+	return c.skipLineDetail
+}
+
+func (c *testConfig) LeaseDuration() int {
+	return c.leaseDuration
+}
+
+func (c *testConfig) LeaderIdentity() string {
+	return c.leaderIdentity
+}
+
+func (c *testConfig) Namespace() string {
+	return "jx"
+}
+
+func (c *testConfig) WatchNamespaces() []string {
+	if len(c.watchNamespaces) == 0 {
+		return []string{c.Namespace()}
+	}
+	return c.watchNamespaces
+}
+
+func (c *testConfig) MergeCoverageKinds() bool {
+	return c.mergeKinds
+}
+
+func (c *testConfig) Attachments() []config.Attachment {
+	return c.attachments
+}
+
+func (c *testConfig) ProcessStatuses() []string {
+	return c.processStatuses
+}
+
+func (c *testConfig) BaseBranch() string {
+	return c.baseBranch
+}
+
+func (c *testConfig) GateRules() string {
+	return c.gateRules
+}
+
+func (c *testConfig) Breakdown() string {
+	return c.breakdown
+}
+
+func (c *testConfig) MaxFactSize() int {
+	return c.maxFactSize
+}
+
+func (c *testConfig) PercentPrecision() int {
+	return c.percentPrecision
+}
+
+func (c *testConfig) MaxRetries() int {
+	return c.maxRetries
+}
+
+func (c *testConfig) FactMode() string {
+	return c.factMode
+}
+
+func (c *testConfig) OverwriteFacts() bool {
+	return c.overwriteFacts
+}
+
 type mockFactInterface struct {
 	createCount int
 }
@@ -67,6 +158,145 @@ func (m *mockFactInterface) PatchUpdate(*jenkinsv1.Fact) (*jenkinsv1.Fact, error
 	return nil, nil
 }
 
+// existingFactInterface is a FactInterface for which the created Fact already exists.
+type existingFactInterface struct {
+	mockFactInterface
+	existing       *jenkinsv1.Fact
+	conflicts      int
+	getCount       int
+	updateCount    int
+	updatedVersion string
+	updated        *jenkinsv1.Fact
+}
+
+func (m *existingFactInterface) Facts(namespace string) jenkinsclientv1.FactInterface {
+	return m
+}
+
+func (m *existingFactInterface) Create(fact *jenkinsv1.Fact) (*jenkinsv1.Fact, error) {
+	m.createCount++
+	return nil, k8serrors.NewAlreadyExists(schema.GroupResource{Group: "jenkins.io", Resource: "facts"}, fact.Name)
+}
+
+func (m *existingFactInterface) Get(name string, options meta_v1.GetOptions) (*jenkinsv1.Fact, error) {
+	m.getCount++
+	return m.existing, nil
+}
+
+func (m *existingFactInterface) Update(fact *jenkinsv1.Fact) (*jenkinsv1.Fact, error) {
+	m.updateCount++
+	if m.updateCount <= m.conflicts {
+		return nil, k8serrors.NewConflict(schema.GroupResource{Group: "jenkins.io", Resource: "facts"}, fact.Name, errors.New("dummy conflict"))
+	}
+	m.updatedVersion = fact.ResourceVersion
+	m.updated = fact
+	return fact, nil
+}
+
+func TestStoreFactKeepsExistingFact(t *testing.T) {
+	fact := &jenkinsv1.Fact{Spec: jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy/new"}}}
+	mock := &existingFactInterface{existing: &jenkinsv1.Fact{}}
+	handler := defaultEventHandler{config: &testConfig{overwriteFacts: false}}
+
+	err := handler.storeFact(fact, mock)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mock.getCount)
+	assert.Equal(t, 0, mock.updateCount)
+}
+
+func TestStoreFactSkipsUnchangedFact(t *testing.T) {
+	measurements := []jenkinsv1.Measurement{{Name: "Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 1}}
+	fact := &jenkinsv1.Fact{Spec: jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy"}, Measurements: measurements}}
+	mock := &existingFactInterface{existing: fact}
+	handler := defaultEventHandler{config: &testConfig{overwriteFacts: true}}
+
+	err := handler.storeFact(fact, mock)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mock.getCount)
+	assert.Equal(t, 0, mock.updateCount)
+}
+
+func TestStoreFactUpdatesChangedFact(t *testing.T) {
+	existing := &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{ResourceVersion: "42"},
+		Spec:       jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy/old"}},
+	}
+	fact := &jenkinsv1.Fact{Spec: jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy/new"}}}
+	mock := &existingFactInterface{existing: existing, conflicts: 2}
+	handler := defaultEventHandler{config: &testConfig{overwriteFacts: true}}
+
+	err := handler.storeFact(fact, mock)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mock.createCount, "conflicting updates should not create the Fact again")
+	assert.Equal(t, 3, mock.updateCount)
+	assert.Equal(t, "42", mock.updatedVersion)
+	assert.Empty(t, fact.ResourceVersion)
+}
+
+func TestStoreFactKeepsForeignMetadata(t *testing.T) {
+	existing := &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{
+			ResourceVersion: "42",
+			Labels:          map[string]string{"team": "dummy", buildLabel: "1"},
+			Annotations:     map[string]string{"note": "dummy"},
+		},
+		Spec: jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy/old"}},
+	}
+	fact := &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Labels:      map[string]string{buildLabel: "2"},
+			Annotations: map[string]string{fingerprintAnnotation: "abc"},
+		},
+		Spec: jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy/new"}},
+	}
+	mock := &existingFactInterface{existing: existing}
+	handler := defaultEventHandler{config: &testConfig{overwriteFacts: true}}
+
+	err := handler.storeFact(fact, mock)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "dummy", buildLabel: "2"}, mock.updated.Labels)
+	assert.Equal(t, map[string]string{"note": "dummy", fingerprintAnnotation: "abc"}, mock.updated.Annotations)
+	assert.Equal(t, "http://dummy/new", mock.updated.Spec.Original.URL)
+	assert.Equal(t, map[string]string{"team": "dummy", buildLabel: "1"}, existing.Labels, "the existing Fact should not be modified")
+}
+
+func TestStoreFactUpdatesChangedFingerprint(t *testing.T) {
+	existing := &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{Annotations: map[string]string{fingerprintAnnotation: "old"}},
+		Spec:       jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy"}},
+	}
+	fact := &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{Annotations: map[string]string{fingerprintAnnotation: "new"}},
+		Spec:       jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy"}},
+	}
+	mock := &existingFactInterface{existing: existing}
+	handler := defaultEventHandler{config: &testConfig{overwriteFacts: true}}
+
+	err := handler.storeFact(fact, mock)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mock.updateCount)
+	assert.Equal(t, "new", mock.updated.Annotations[fingerprintAnnotation])
+}
+
+// invalidFactInterface is a FactInterface rejecting all created Facts as invalid.
+type invalidFactInterface struct {
+	mockFactInterface
+}
+
+func (m *invalidFactInterface) Create(fact *jenkinsv1.Fact) (*jenkinsv1.Fact, error) {
+	m.createCount++
+	return nil, k8serrors.NewInvalid(schema.GroupKind{Group: "jenkins.io", Kind: "Fact"}, fact.Name, nil)
+}
+
+func TestStoreFactDoesNotRetryInvalidFact(t *testing.T) {
+	mock := &invalidFactInterface{}
+	handler := defaultEventHandler{}
+
+	err := handler.storeFact(dummyFact, mock)
+	assert.True(t, k8serrors.IsInvalid(err))
+	assert.Equal(t, 1, mock.createCount)
+}
+
 func TestUpdatePipelineUpdateOperationIsRetried(t *testing.T) {
 	mock := &mockFactInterface{}
 	handler := defaultEventHandler{}
@@ -88,17 +318,132 @@ func TestCreateFact(t *testing.T) {
 
 	url := "http://dummy"
 
-	handler := defaultEventHandler{}
+	handler := defaultEventHandler{config: &testConfig{}}
 	fact := handler.createFact(report, pipelineActivity, url)
 
 	expectedName := fmt.Sprintf("%s-%s-%s", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name)
 	assert.Equal(t, expectedName, fact.Spec.Name)
 	assert.Equal(t, url, fact.Spec.Original.URL)
 	assert.Equal(t, jenkinsv1.FactTypeCoverage, fact.Spec.FactType)
-	assert.Len(t, fact.Spec.Measurements, 3)
+	assert.Len(t, fact.Spec.Measurements, 4)
 	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Instructions-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 90})
 	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Instructions-Missed", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10})
 	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Instructions-Total", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 100})
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Instructions-Percent", MeasurementType: jenkinsv1.MeasurementPercent, MeasurementValue: 90})
+}
+
+func TestCreatePercentMeasurement(t *testing.T) {
+	var tests = []struct {
+		covered   int
+		total     int
+		precision int
+		expected  int
+	}{
+		{2, 3, 0, 66},
+		{2, 3, 2, 6666},
+		{1, 8, 1, 125},
+		{999, 1000, 0, 99},
+		{10, 10, 0, 100},
+		{0, 10, 0, 0},
+		{0, 0, 0, 100},
+		{0, 0, 2, 10000},
+	}
+
+	handler := defaultEventHandler{}
+	for _, test := range tests {
+		measurement := handler.createPercentMeasurement(jenkinsv1.CodeCoverageCountTypeLines, test.covered, test.total, test.precision)
+		assert.Equal(t, "Lines-Percent", measurement.Name)
+		assert.Equal(t, jenkinsv1.MeasurementPercent, measurement.MeasurementType)
+		assert.Equal(t, test.expected, measurement.MeasurementValue, "covered %d of %d with precision %d", test.covered, test.total, test.precision)
+	}
+}
+
+func TestCreateAggregatedFact(t *testing.T) {
+	pipelineActivity := getFakePipelineActivity(t)
+	reports := []report.Report{
+		{Name: "module-a", Counters: []report.Counter{{Type: "LINE", Missed: 10, Covered: 30}}},
+		{Name: "module-b", Counters: []report.Counter{{Type: "LINE", Missed: 20, Covered: 40}}},
+	}
+	urls := []string{"http://dummy/module-a/jacoco.xml", "http://dummy/module-b/jacoco.xml"}
+
+	handler := defaultEventHandler{config: &testConfig{}}
+	fact := handler.createAggregatedFact(reports, urls, pipelineActivity, "", kindUnit)
+
+	expectedName := fmt.Sprintf("%s-%s-%s", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name)
+	assert.Equal(t, expectedName, fact.Spec.Name)
+	assert.Equal(t, urls[0], fact.Spec.Original.URL)
+	assert.Len(t, fact.Spec.Measurements, 12)
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 70})
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Total", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 100})
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "module-a/Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 30, Tags: []string{moduleTag}})
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "module-b/Lines-Missed", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 20, Tags: []string{moduleTag}})
+}
+
+func TestCreateFactsPerModule(t *testing.T) {
+	pipelineActivity := getFakePipelineActivity(t)
+	reports := []report.Report{
+		{Name: "module-a", Counters: []report.Counter{{Type: "LINE", Missed: 10, Covered: 30}}},
+		{Name: "module-b", Counters: []report.Counter{{Type: "LINE", Missed: 20, Covered: 40}}},
+	}
+	urls := []string{"http://dummy/module-a/jacoco.xml", "http://dummy/module-b/jacoco.xml"}
+
+	handler := defaultEventHandler{config: &testConfig{factMode: config.FactModeModule}}
+	facts := handler.createFacts(reports, urls, pipelineActivity, "", kindUnit)
+
+	assert.Len(t, facts, 2)
+	for i, module := range []string{"module-a", "module-b"} {
+		expectedName := fmt.Sprintf("%s-%s-%s-%s", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name, module)
+		assert.Equal(t, expectedName, facts[i].Name)
+		assert.Equal(t, expectedName, facts[i].Spec.Name)
+		assert.Equal(t, urls[i], facts[i].Spec.Original.URL)
+		assert.Equal(t, module, facts[i].Labels[moduleTag])
+		assert.Equal(t, pipelineActivity.Name, facts[i].Labels["pipelineName"])
+		assert.Len(t, facts[i].Spec.Measurements, 4)
+	}
+}
+
+func TestCreateFactsWithTag(t *testing.T) {
+	pipelineActivity := getFakePipelineActivity(t)
+	reports := []report.Report{{Name: "module-a"}, {Name: "module-b"}}
+	urls := []string{"http://dummy/module-a/jacoco-it.xml", "http://dummy/module-b/jacoco-it.xml"}
+
+	handler := defaultEventHandler{config: &testConfig{factMode: config.FactModeModule}}
+	facts := handler.createFacts(reports, urls, pipelineActivity, "integration", kindIntegration)
+
+	assert.Len(t, facts, 2)
+	assert.Equal(t, fmt.Sprintf("%s-%s-%s-integration-module-a", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name), facts[0].Name)
+	assert.Equal(t, []string{appName, "integration", moduleTag}, facts[0].Spec.Tags)
+
+	handler = defaultEventHandler{config: &testConfig{factMode: config.FactModeMerged}}
+	facts = handler.createFacts(reports, urls, pipelineActivity, "integration", kindIntegration)
+
+	assert.Len(t, facts, 1)
+	assert.Equal(t, fmt.Sprintf("%s-%s-%s-integration", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name), facts[0].Spec.Name)
+	assert.Equal(t, []string{appName, "integration"}, facts[0].Spec.Tags)
+}
+
+func TestAttachmentConfig(t *testing.T) {
+	attachments, _ := config.ParseAttachments("jacoco:jacoco,jacoco-it:jacoco:integration")
+	handler := defaultEventHandler{config: &testConfig{attachments: attachments}}
+
+	attachment, ok := handler.attachmentConfig("jacoco-it")
+	assert.True(t, ok)
+	assert.Equal(t, config.Attachment{Name: "jacoco-it", Format: report.FormatJaCoCo, Tag: "integration"}, attachment)
+
+	_, ok = handler.attachmentConfig("coverage")
+	assert.False(t, ok)
+}
+
+func TestCreateFactsMerged(t *testing.T) {
+	pipelineActivity := getFakePipelineActivity(t)
+	reports := []report.Report{{Name: "module-a"}, {Name: "module-b"}}
+	urls := []string{"http://dummy/module-a/jacoco.xml", "http://dummy/module-b/jacoco.xml"}
+
+	handler := defaultEventHandler{config: &testConfig{factMode: config.FactModeMerged}}
+	facts := handler.createFacts(reports, urls, pipelineActivity, "", kindUnit)
+
+	assert.Len(t, facts, 1)
+	assert.Equal(t, fmt.Sprintf("%s-%s-%s", appName, jenkinsv1.FactTypeCoverage, pipelineActivity.Name), facts[0].Name)
 }
 
 // GetFakePipelineActivity returns a PipelineActivity with fake data
@@ -110,3 +455,75 @@ func getFakePipelineActivity(t *testing.T) *jenkinsv1.PipelineActivity {
 	}
 	return activity
 }
+
+func TestEnqueueDeduplicatesActivities(t *testing.T) {
+	handler := defaultEventHandler{queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())}
+	defer handler.queue.ShutDown()
+
+	activity := &jenkinsv1.PipelineActivity{ObjectMeta: meta_v1.ObjectMeta{Namespace: "jx", Name: "foo-bar-master-1"}}
+	handler.Add(activity)
+	handler.Update(activity, activity)
+
+	assert.Equal(t, 1, handler.queue.Len())
+	key, _ := handler.queue.Get()
+	assert.Equal(t, "jx/foo-bar-master-1", key)
+}
+
+func TestHandleErrRequeuesUntilMaxRetries(t *testing.T) {
+	handler := defaultEventHandler{
+		config: &testConfig{maxRetries: 2},
+		queue:  workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0)),
+	}
+	defer handler.queue.ShutDown()
+
+	key := "jx/foo-bar-master-1"
+	handler.handleErr(errors.New("dummy error"), key)
+	handler.handleErr(errors.New("dummy error"), key)
+	assert.Equal(t, 2, handler.queue.NumRequeues(key))
+
+	handler.handleErr(errors.New("dummy error"), key)
+	assert.Equal(t, 0, handler.queue.NumRequeues(key), "the key should be forgotten after the last retry")
+
+	handler.handleErr(errors.New("dummy error"), key)
+	handler.handleErr(nil, key)
+	assert.Equal(t, 0, handler.queue.NumRequeues(key), "the key should be forgotten after a successful retry")
+}
+
+func TestOnPipelineActivitySkipsRunningActivity(t *testing.T) {
+	pipelineActivity := &jenkinsv1.PipelineActivity{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "foo-bar-master-1"},
+		Spec: jenkinsv1.PipelineActivitySpec{
+			Status:      jenkinsv1.ActivityStatusTypeRunning,
+			Attachments: []jenkinsv1.Attachment{{Name: appName, URLs: []string{"http://dummy/jacoco.xml"}}},
+		},
+	}
+
+	// without a facts client the activity would fail if it was processed
+	handler := defaultEventHandler{config: &testConfig{processStatuses: []string{"Succeeded", "Failed", "Aborted"}}, processed: newProcessedCache()}
+
+	assert.NoError(t, handler.onPipelineActivity(pipelineActivity))
+}
+
+func TestDecodeOptions(t *testing.T) {
+	attachments, _ := config.ParseAttachments("jacoco:jacoco:unit,jacoco-it:jacoco:integration")
+	unit := []jenkinsv1.Attachment{{Name: "jacoco", URLs: []string{"http://dummy/jacoco.xml"}}}
+	unitAndIntegration := append(unit, jenkinsv1.Attachment{Name: "jacoco-it", URLs: []string{"http://dummy/jacoco-it.xml"}})
+
+	var testCases = []struct {
+		config       *testConfig
+		attachments  []jenkinsv1.Attachment
+		skipPackages bool
+	}{
+		{&testConfig{attachments: attachments, breakdown: config.BreakdownNone, mergeKinds: true}, unit, true},
+		{&testConfig{attachments: attachments, breakdown: config.BreakdownNone, gateRules: "LINE >= 80%"}, unit, true},
+		{&testConfig{attachments: attachments, breakdown: config.BreakdownNone, mergeKinds: false}, unitAndIntegration, true},
+		{&testConfig{attachments: attachments, breakdown: config.BreakdownPackage}, unit, false},
+		{&testConfig{attachments: attachments, breakdown: config.BreakdownNone, gateRules: "package:LINE >= 50%"}, unit, false},
+		{&testConfig{attachments: attachments, breakdown: config.BreakdownNone, mergeKinds: true}, unitAndIntegration, false},
+	}
+
+	for i, testCase := range testCases {
+		handler := defaultEventHandler{config: testCase.config}
+		assert.Equal(t, testCase.skipPackages, handler.decodeOptions(testCase.attachments).SkipPackages, "test case %d", i)
+	}
+}
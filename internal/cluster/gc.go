@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsv1types "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	activityAPIVersion = "jenkins.io/v1"
+	activityKind       = "PipelineActivity"
+)
+
+// ownerReference returns a reference to the specified pipeline activity as owner of its Facts, so that the Kubernetes
+// garbage collector deletes the Facts together with the activity.
+func ownerReference(pipelineActivity *jenkinsv1.PipelineActivity) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: activityAPIVersion,
+		Kind:       activityKind,
+		Name:       pipelineActivity.Name,
+		UID:        pipelineActivity.UID,
+	}
+}
+
+// isCoverageFact returns true if the specified Fact was created by this app.
+func isCoverageFact(fact jenkinsv1.Fact) bool {
+	return fact.Spec.FactType == jenkinsv1.FactTypeCoverage && util.Contains(fact.Spec.Tags, appName)
+}
+
+// deleteFacts deletes the coverage Facts of the pipeline activity with the specified namespace and name.
+func deleteFacts(factsGetter jenkinsv1types.FactsGetter, namespace string, activityName string) (int, error) {
+	selector := fmt.Sprintf("%s=%s,%s=%s", subjectKindLabel, activityKind, pipelineNameLabel, labelValue(activityName))
+	facts, err := factsGetter.Facts(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, err
+	}
+
+	var orphans []jenkinsv1.Fact
+	for _, fact := range facts.Items {
+		if isCoverageFact(fact) && fact.Spec.SubjectReference.Name == activityName {
+			orphans = append(orphans, fact)
+		}
+	}
+	return deleteOrphans(factsGetter, orphans)
+}
+
+// sweepOrphanedFacts deletes the coverage Facts in the specified namespace whose pipeline activity is not one of the
+// specified existing activities. It returns the UIDs of the deleted activities the orphaned Facts referred to.
+func sweepOrphanedFacts(factsGetter jenkinsv1types.FactsGetter, namespace string, activities []interface{}) ([]types.UID, error) {
+	existing := map[types.UID]bool{}
+	for _, obj := range activities {
+		if pipelineActivity, ok := obj.(*jenkinsv1.PipelineActivity); ok {
+			existing[pipelineActivity.UID] = true
+		}
+	}
+
+	facts, err := factsGetter.Facts(namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", subjectKindLabel, activityKind)})
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []jenkinsv1.Fact
+	var uids []types.UID
+	for _, fact := range facts.Items {
+		uid := fact.Spec.SubjectReference.UID
+		if !isCoverageFact(fact) || uid == "" || existing[uid] {
+			continue
+		}
+		orphans = append(orphans, fact)
+		uids = append(uids, uid)
+	}
+	_, err = deleteOrphans(factsGetter, orphans)
+	return uids, err
+}
+
+// deleteOrphans deletes the specified Facts from their namespaces, ignoring Facts which are already deleted, together
+// with the coverage gauges set from them. It returns the number of deleted Facts.
+func deleteOrphans(factsGetter jenkinsv1types.FactsGetter, facts []jenkinsv1.Fact) (int, error) {
+	var errs util.MultiError
+	count := 0
+	for _, fact := range facts {
+		err := factsGetter.Facts(fact.Namespace).Delete(fact.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs.Collect(fmt.Errorf("unable to delete fact '%s': %s", fact.Name, err))
+			continue
+		}
+		logger.Infof("deleted fact '%s' of deleted pipeline activity '%s'", fact.Name, fact.Spec.SubjectReference.Name)
+		forgetCoverage(&fact)
+		count++
+	}
+	return count, errs.ToError()
+}
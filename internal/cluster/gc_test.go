@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func coverageFact(name string, activityName string, uid types.UID) jenkinsv1.Fact {
+	return jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"subjectkind":  "PipelineActivity",
+				"pipelineName": activityName,
+			},
+		},
+		Spec: jenkinsv1.FactSpec{
+			FactType:         jenkinsv1.FactTypeCoverage,
+			Tags:             []string{appName},
+			SubjectReference: jenkinsv1.ResourceReference{Name: activityName, UID: uid},
+		},
+	}
+}
+
+func TestCreateFactIsOwnedByActivity(t *testing.T) {
+	activity := &jenkinsv1.PipelineActivity{ObjectMeta: meta_v1.ObjectMeta{Name: "foo-bar-master-1", UID: "uid"}}
+
+	handler := defaultEventHandler{config: &testConfig{}}
+	fact := handler.createFact(report.Report{}, activity, "http://dummy/jacoco.xml")
+
+	assert.Equal(t, []meta_v1.OwnerReference{{APIVersion: "jenkins.io/v1", Kind: "PipelineActivity", Name: "foo-bar-master-1", UID: "uid"}}, fact.OwnerReferences)
+}
+
+func TestDeleteFacts(t *testing.T) {
+	otherApp := coverageFact("other-jx.coverage-foo-bar-master-1", "foo-bar-master-1", "uid-1")
+	otherApp.Spec.Tags = []string{"other"}
+	factsInterface := &listingFactInterface{facts: []jenkinsv1.Fact{
+		coverageFact("jacoco-jx.coverage-foo-bar-master-1", "foo-bar-master-1", "uid-1"),
+		coverageFact("jacoco-jx.coverage-foo-bar-master-1-module-a", "foo-bar-master-1", "uid-1"),
+		coverageFact("jacoco-jx.coverage-foo-bar-master-2", "foo-bar-master-2", "uid-2"),
+		otherApp,
+	}}
+
+	count, err := deleteFacts(factsInterface, "jx", "foo-bar-master-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"jacoco-jx.coverage-foo-bar-master-1", "jacoco-jx.coverage-foo-bar-master-1-module-a"}, factsInterface.deleted)
+}
+
+func TestSweepOrphanedFacts(t *testing.T) {
+	factsInterface := &listingFactInterface{facts: []jenkinsv1.Fact{
+		coverageFact("jacoco-jx.coverage-foo-bar-master-1", "foo-bar-master-1", "uid-1"),
+		coverageFact("jacoco-jx.coverage-foo-bar-master-2", "foo-bar-master-2", "uid-2"),
+	}}
+	activities := []interface{}{
+		&jenkinsv1.PipelineActivity{ObjectMeta: meta_v1.ObjectMeta{Name: "foo-bar-master-2", UID: "uid-2"}},
+	}
+
+	uids, err := sweepOrphanedFacts(factsInterface, "jx", activities)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []types.UID{"uid-1"}, uids)
+	assert.Equal(t, []string{"jacoco-jx.coverage-foo-bar-master-1"}, factsInterface.deleted)
+}
@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"fmt"
+	jenkinsv1types "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"sync"
+	"time"
+)
+
+const (
+	// ProgressTimeout is the time after which the event loop is considered stuck, if none of the queued pipeline
+	// activities has been processed.
+	ProgressTimeout = time.Minute * 10
+)
+
+// health tracks the state of the event handler reported to the liveness and readiness probes.
+type health struct {
+	sync.Mutex
+	started bool
+	synced  []cache.InformerSynced
+	// indexers are the indexers of the informers, counting the cached pipeline activities
+	indexers []cache.Indexer
+	// lastEvent is the time the informers last delivered an event
+	lastEvent time.Time
+	// lastProgress is the time a worker last started or finished processing a pipeline activity
+	lastProgress time.Time
+	inFlight     int
+	now          func() time.Time
+}
+
+func (s *health) time() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// start records that the informers with the specified synced functions and indexers have been started.
+func (s *health) start(synced []cache.InformerSynced, indexers []cache.Indexer) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.started = true
+	s.synced = synced
+	s.indexers = indexers
+	s.lastEvent = s.time()
+	s.lastProgress = s.lastEvent
+}
+
+// event records that the informers delivered an event.
+func (s *health) event() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastEvent = s.time()
+}
+
+// processing records that a worker started processing a pipeline activity.
+func (s *health) processing() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.inFlight++
+	s.lastProgress = s.time()
+}
+
+// processed records that a worker finished processing a pipeline activity.
+func (s *health) processed() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.inFlight--
+	s.lastProgress = s.time()
+}
+
+// hasSynced returns true if the informers have synced their caches. An event handler which has not been started yet,
+// eg while waiting for the leader lease, is considered synced.
+func (s *health) hasSynced() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, synced := range s.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// stalled returns an error if the specified number of queued pipeline activities have not been processed or the
+// informers have not delivered an event, not even a resync of the cached activities, for too long.
+func (s *health) stalled(queued int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.started {
+		return nil
+	}
+	now := s.time()
+	if (queued > 0 || s.inFlight > 0) && now.Sub(s.lastProgress) > ProgressTimeout {
+		return fmt.Errorf("no pipeline activity processed for %s, %d queued and %d in progress", now.Sub(s.lastProgress).Round(time.Second), queued, s.inFlight)
+	}
+	if now.Sub(s.lastEvent) > 2*SyncPeriod && s.cachedActivities() > 0 {
+		return fmt.Errorf("no pipeline activity events received for %s", now.Sub(s.lastEvent).Round(time.Second))
+	}
+	return nil
+}
+
+// Ready returns an error if the pipeline activity caches have not been synced or the Fact API cannot be reached.
+func (h *defaultEventHandler) Ready() error {
+	if !h.health.hasSynced() {
+		return fmt.Errorf("pipeline activity cache not synced")
+	}
+	for _, namespace := range watchNamespaces(h.config) {
+		if err := pingFacts(h.facts.Facts(namespace)); err != nil {
+			return fmt.Errorf("unable to list facts in %s: %s", describeNamespace(namespace), err)
+		}
+	}
+	return nil
+}
+
+// Alive returns an error if the event loop stopped making progress.
+func (h *defaultEventHandler) Alive() error {
+	return h.health.stalled(h.queue.Len())
+}
+
+// cachedActivities returns the number of pipeline activities in the caches of the informers. The caller needs to hold
+// the lock.
+func (s *health) cachedActivities() int {
+	cached := 0
+	for _, indexer := range s.indexers {
+		cached += len(indexer.ListKeys())
+	}
+	return cached
+}
+
+// pingFacts checks that the Facts of the specified interface can be listed.
+func pingFacts(factsInterface jenkinsv1types.FactInterface) error {
+	_, err := factsInterface.List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", subjectKindLabel, activityKind), Limit: 1})
+	return err
+}
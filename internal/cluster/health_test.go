@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	synced := false
+	handler := defaultEventHandler{config: &testConfig{}, facts: &listingFactInterface{}}
+	assert.NoError(t, handler.Ready(), "a handler waiting to be started should be ready")
+
+	handler.health.start([]cache.InformerSynced{func() bool { return synced }}, nil)
+	assert.EqualError(t, handler.Ready(), "pipeline activity cache not synced")
+
+	synced = true
+	assert.NoError(t, handler.Ready())
+
+	handler.facts = &mockFactInterface{}
+	assert.EqualError(t, handler.Ready(), "unable to list facts in namespace 'jx': not implemented")
+}
+
+func TestAlive(t *testing.T) {
+	now := time.Now()
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	defer queue.ShutDown()
+	handler := defaultEventHandler{queue: queue, health: health{now: func() time.Time { return now }}}
+	assert.NoError(t, handler.Alive(), "a handler waiting to be started should be alive")
+
+	handler.health.start(nil, nil)
+	now = now.Add(ProgressTimeout * 2)
+	assert.NoError(t, handler.Alive(), "an idle handler should be alive")
+
+	queue.Add("jx/foo-bar-master-1")
+	assert.EqualError(t, handler.Alive(), "no pipeline activity processed for 20m0s, 1 queued and 0 in progress")
+
+	handler.health.processing()
+	assert.NoError(t, handler.Alive())
+
+	now = now.Add(ProgressTimeout + time.Second)
+	assert.EqualError(t, handler.Alive(), "no pipeline activity processed for 10m1s, 1 queued and 1 in progress")
+}
+
+func TestAliveWithoutEvents(t *testing.T) {
+	now := time.Now()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(activity("foo-bar-master-1", "master", "1")))
+	handler := defaultEventHandler{
+		queue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+		health: health{now: func() time.Time { return now }},
+	}
+	handler.health.start(nil, []cache.Indexer{indexer})
+
+	now = now.Add(SyncPeriod)
+	assert.NoError(t, handler.Alive())
+
+	now = now.Add(SyncPeriod * 2)
+	assert.EqualError(t, handler.Alive(), "no pipeline activity events received for 30m0s")
+
+	handler.health.event()
+	assert.NoError(t, handler.Alive())
+}
+
+func TestAliveWhileStarting(t *testing.T) {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	defer queue.ShutDown()
+	handler := defaultEventHandler{queue: queue}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			_ = indexer.Add(activity(fmt.Sprintf("foo-bar-master-%d", i), "master", strconv.Itoa(i)))
+			handler.health.start(nil, []cache.Indexer{indexer})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, handler.Alive())
+	}
+	<-done
+}
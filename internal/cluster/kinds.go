@@ -0,0 +1,148 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"net/url"
+	"path"
+	"regexp"
+)
+
+const (
+	// kindUnit is the coverage kind of unit tests.
+	kindUnit = "unit"
+
+	// kindIntegration is the coverage kind of integration tests.
+	kindIntegration = "integration"
+
+	// kindE2E is the coverage kind of end-to-end tests.
+	kindE2E = "e2e"
+
+	// kindMerged is the coverage kind of the Fact combining the coverage of all kinds.
+	kindMerged = "merged"
+
+	coverageKindLabel = "coverageKind"
+)
+
+var (
+	// kindPatterns maps attachment names and report file names to coverage kinds, eg jacoco-it.xml to integration.
+	kindPatterns = []struct {
+		pattern *regexp.Regexp
+		kind    string
+	}{
+		{regexp.MustCompile(`(?i)(^|[-_.])(it|integration)([-_.]|$)`), kindIntegration},
+		{regexp.MustCompile(`(?i)(^|[-_.])e2e([-_.]|$)`), kindE2E},
+		{regexp.MustCompile(`(?i)(^|[-_.])(ut|unit)([-_.]|$)`), kindUnit},
+	}
+)
+
+// kindGroup is a group of the reports of an attachment containing the same kind of coverage.
+type kindGroup struct {
+	kind string
+	// indices are the positions of the reports of the group in the URLs of the attachment
+	indices []int
+}
+
+// urls returns the URLs of the reports of the group.
+func (g kindGroup) urls(urls []string) []string {
+	groupURLs := make([]string, 0, len(g.indices))
+	for _, i := range g.indices {
+		groupURLs = append(groupURLs, urls[i])
+	}
+	return groupURLs
+}
+
+// reports returns the reports of the group.
+func (g kindGroup) reports(reports []report.Report) []report.Report {
+	groupReports := make([]report.Report, 0, len(g.indices))
+	for _, i := range g.indices {
+		groupReports = append(groupReports, reports[i])
+	}
+	return groupReports
+}
+
+// kindGroups groups the report URLs of an attachment by the kind of coverage they contain, eg a jacoco.xml and a
+// jacoco-it.xml stashed together are split into a unit and an integration group. The groups are ordered by their first
+// report.
+func kindGroups(attachmentConfig config.Attachment, urls []string) []kindGroup {
+	var groups []kindGroup
+	positions := map[string]int{}
+	for i, reportURL := range urls {
+		kind := coverageKind(attachmentConfig, reportURL)
+		position, ok := positions[kind]
+		if !ok {
+			position = len(groups)
+			positions[kind] = position
+			groups = append(groups, kindGroup{kind: kind})
+		}
+		groups[position].indices = append(groups[position].indices, i)
+	}
+	return groups
+}
+
+// coverageKind returns the kind of coverage the report with the specified URL of an attachment contains. The kind is
+// taken from the configured tag of the attachment, the file name of the report, the directory containing the report or
+// the attachment name, in this order, so that eg the report target/site/jacoco-it/jacoco.xml of the Maven failsafe
+// plugin is classified as integration test report. Reports without any indication of their kind are assumed to be
+// unit test reports.
+func coverageKind(attachmentConfig config.Attachment, reportURL string) string {
+	if util.Contains([]string{kindUnit, kindIntegration, kindE2E}, attachmentConfig.Tag) {
+		return attachmentConfig.Tag
+	}
+
+	p := reportURL
+	if u, err := url.Parse(reportURL); err == nil {
+		p = u.Path
+	}
+	for _, name := range []string{path.Base(p), path.Base(path.Dir(p)), attachmentConfig.Name} {
+		for _, kindPattern := range kindPatterns {
+			if kindPattern.pattern.MatchString(name) {
+				return kindPattern.kind
+			}
+		}
+	}
+	return kindUnit
+}
+
+// kindTag returns the tag of the Facts of the specified kind created from an attachment containing several kinds of
+// coverage, eg integration or web-integration for an attachment tagged web.
+func kindTag(tag string, kind string) string {
+	if tag == "" {
+		return kind
+	}
+	return tag + "-" + kind
+}
+
+// kindFact labels and tags the Fact with the specified coverage kind.
+func kindFact(fact *jenkinsv1.Fact, kind string) {
+	if fact.Labels == nil {
+		fact.Labels = map[string]string{}
+	}
+	fact.Labels[coverageKindLabel] = kind
+	if !util.Contains(fact.Spec.Tags, kind) {
+		fact.Spec.Tags = append(fact.Spec.Tags, kind)
+	}
+}
+
+// mergeKinds merges the reports of several coverage kinds. The reports of the same module are merged line by line, so
+// that a line covered by any kind of test counts as covered, and the merged modules are combined, so that the
+// coverage of distinct modules adds up.
+func mergeKinds(reports []report.Report, urls []string) report.Report {
+	modules := map[string][]report.Report{}
+	var ids []string
+	for i, r := range reports {
+		id := moduleID(r, urls[i])
+		if _, ok := modules[id]; !ok {
+			ids = append(ids, id)
+		}
+		modules[id] = append(modules[id], r)
+	}
+
+	merged := make([]report.Report, 0, len(ids))
+	for _, id := range ids {
+		merged = append(merged, report.Merge(modules[id]...))
+	}
+	return report.Combine(merged...)
+}
@@ -0,0 +1,164 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsclientv1 "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// creatingFactInterface is a FactInterface recording the created Facts.
+type creatingFactInterface struct {
+	mockFactInterface
+	created []*jenkinsv1.Fact
+}
+
+func (m *creatingFactInterface) Facts(namespace string) jenkinsclientv1.FactInterface {
+	return m
+}
+
+func (m *creatingFactInterface) Create(fact *jenkinsv1.Fact) (*jenkinsv1.Fact, error) {
+	m.created = append(m.created, fact)
+	return fact, nil
+}
+
+func TestCoverageKind(t *testing.T) {
+	var testCases = []struct {
+		attachment   config.Attachment
+		url          string
+		expectedKind string
+	}{
+		{config.Attachment{Name: "jacoco", Tag: "e2e"}, "http://dummy/jacoco.xml", "e2e"},
+		{config.Attachment{Name: "jacoco-it"}, "http://dummy/jacoco.xml", "integration"},
+		{config.Attachment{Name: "coverage"}, "http://dummy/target/site/jacoco-it/jacoco-it.xml", "integration"},
+		{config.Attachment{Name: "coverage"}, "http://dummy/target/site/jacoco-it/jacoco.xml", "integration"},
+		{config.Attachment{Name: "coverage"}, "http://dummy/e2e.lcov.info", "e2e"},
+		{config.Attachment{Name: "jacoco-ut"}, "http://dummy/jacoco.xml", "unit"},
+		{config.Attachment{Name: "jacoco-it"}, "http://dummy/jacoco-ut/jacoco.xml", "unit"},
+		{config.Attachment{Name: "jacoco"}, "http://dummy/jacoco.xml", "unit"},
+		{config.Attachment{Name: "jacoco", Tag: "web"}, "http://dummy/split/jacoco.xml", "unit"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedKind, coverageKind(testCase.attachment, testCase.url), testCase.url)
+	}
+}
+
+func TestCreateKindFactsOfSeveralKindsInOneAttachment(t *testing.T) {
+	attachments, _ := config.ParseAttachments("jacoco")
+	handler := defaultEventHandler{config: &testConfig{attachments: attachments, mergeKinds: true}}
+	activity := &jenkinsv1.PipelineActivity{Spec: jenkinsv1.PipelineActivitySpec{Attachments: []jenkinsv1.Attachment{
+		{Name: "jacoco", URLs: []string{"http://dummy/jacoco/jacoco.xml", "http://dummy/jacoco/jacoco-it.xml"}},
+	}}}
+	activity.Name = "foo-bar-master-1"
+	reports := []report.Report{
+		{Counters: []report.Counter{{Type: "LINE", Missed: 2, Covered: 8}}},
+		{Counters: []report.Counter{{Type: "LINE", Missed: 5, Covered: 5}}},
+	}
+
+	facts := handler.createKindFacts(reports, activity.Spec.Attachments[0], attachments[0], activity)
+
+	assert.Len(t, facts, 2)
+	assert.Equal(t, "jacoco-jx.coverage-foo-bar-master-1-unit", facts[0].Name)
+	assert.Equal(t, kindUnit, facts[0].Labels[coverageKindLabel])
+	assert.Equal(t, "http://dummy/jacoco/jacoco.xml", facts[0].Spec.Original.URL)
+	assert.Contains(t, facts[0].Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Total", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10})
+	assert.Equal(t, "jacoco-jx.coverage-foo-bar-master-1-integration", facts[1].Name)
+	assert.Equal(t, kindIntegration, facts[1].Labels[coverageKindLabel])
+	assert.Equal(t, "http://dummy/jacoco/jacoco-it.xml", facts[1].Spec.Original.URL)
+	assert.Contains(t, facts[1].Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Total", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 10})
+
+	// the kinds of the attachment are merged and need the packages of the reports
+	assert.Len(t, handler.coverageKinds(activity.Spec.Attachments), 2)
+	assert.False(t, handler.decodeOptions(activity.Spec.Attachments).SkipPackages)
+}
+
+func TestStoreMergedFact(t *testing.T) {
+	attachments, _ := config.ParseAttachments("jacoco:jacoco:unit,jacoco-it:jacoco:integration")
+	factsInterface := &creatingFactInterface{}
+	handler := defaultEventHandler{
+		config:    &testConfig{attachments: attachments, mergeKinds: true},
+		facts:     factsInterface,
+		processed: newProcessedCache(),
+	}
+
+	activity := &jenkinsv1.PipelineActivity{Spec: jenkinsv1.PipelineActivitySpec{Attachments: []jenkinsv1.Attachment{
+		{Name: "jacoco", URLs: []string{"http://dummy/jacoco.xml"}},
+		{Name: "jacoco-it", URLs: []string{"http://dummy/jacoco-it.xml"}},
+	}}}
+	activity.Name = "foo-bar-master-1"
+	activity.UID = "uid"
+	sourceFile := func(lines ...report.Line) []report.Package {
+		return []report.Package{{Name: "p", SourceFiles: []report.SourceFile{{Name: "A.java", Lines: lines}}}}
+	}
+	retrieved := map[string][]report.Report{
+		"jacoco":    {{Format: report.FormatJaCoCo, Packages: sourceFile(report.Line{Nr: 1, Ci: 1}, report.Line{Nr: 2, Mi: 1})}},
+		"jacoco-it": {{Format: report.FormatJaCoCo, Packages: sourceFile(report.Line{Nr: 1, Mi: 1}, report.Line{Nr: 2, Ci: 1})}},
+	}
+
+	err := handler.storeMergedFact(activity.Spec.Attachments, retrieved, activity)
+
+	assert.NoError(t, err)
+	assert.Len(t, factsInterface.created, 1)
+	fact := factsInterface.created[0]
+	assert.Equal(t, "jacoco-jx.coverage-foo-bar-master-1-merged", fact.Name)
+	assert.Equal(t, kindMerged, fact.Labels[coverageKindLabel])
+	assert.Contains(t, fact.Spec.Tags, kindMerged)
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 2})
+	assert.Contains(t, fact.Spec.Measurements, jenkinsv1.Measurement{Name: "Lines-Missed", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: 0})
+
+	// the merged coverage is not stored again for unchanged attachments
+	assert.NoError(t, handler.storeMergedFact(activity.Spec.Attachments, retrieved, activity))
+	assert.Len(t, factsInterface.created, 1)
+}
+
+func TestStoreMergedFactRequiresSeveralKinds(t *testing.T) {
+	attachments, _ := config.ParseAttachments("jacoco:jacoco:unit")
+	handler := defaultEventHandler{config: &testConfig{attachments: attachments, mergeKinds: true}, processed: newProcessedCache()}
+
+	activity := &jenkinsv1.PipelineActivity{Spec: jenkinsv1.PipelineActivitySpec{Attachments: []jenkinsv1.Attachment{
+		{Name: "jacoco", URLs: []string{"http://dummy/jacoco.xml"}},
+	}}}
+
+	assert.NoError(t, handler.storeMergedFact(activity.Spec.Attachments, nil, activity))
+}
+
+func TestStoreMergedFactRequiresLineDetail(t *testing.T) {
+	attachments, _ := config.ParseAttachments("jacoco:jacoco:unit,jacoco-it:jacoco:integration")
+	factsInterface := &creatingFactInterface{}
+	handler := defaultEventHandler{
+		config:    &testConfig{attachments: attachments, mergeKinds: true, skipLineDetail: true},
+		facts:     factsInterface,
+		processed: newProcessedCache(),
+	}
+
+	activity := &jenkinsv1.PipelineActivity{Spec: jenkinsv1.PipelineActivitySpec{Attachments: []jenkinsv1.Attachment{
+		{Name: "jacoco", URLs: []string{"http://dummy/jacoco.xml"}},
+		{Name: "jacoco-it", URLs: []string{"http://dummy/jacoco-it.xml"}},
+	}}}
+
+	assert.NoError(t, handler.storeMergedFact(activity.Spec.Attachments, nil, activity))
+	assert.Empty(t, factsInterface.created)
+}
+
+func TestMergeKindsPerModule(t *testing.T) {
+	module := func(name string, lines ...report.Line) report.Report {
+		return report.Report{Name: name, Format: report.FormatJaCoCo, Packages: []report.Package{
+			{Name: "com/acme", SourceFiles: []report.SourceFile{{Name: "Application.java", Lines: lines, Counters: []report.Counter{{Type: "LINE"}}}}},
+		}}
+	}
+	reports := []report.Report{
+		module("module-a", report.Line{Nr: 1, Ci: 1}, report.Line{Nr: 2, Mi: 1}),
+		module("module-b", report.Line{Nr: 1, Mi: 1}, report.Line{Nr: 2, Mi: 1}),
+		module("module-a", report.Line{Nr: 1, Mi: 1}, report.Line{Nr: 2, Ci: 1}),
+		module("module-b", report.Line{Nr: 1, Mi: 1}, report.Line{Nr: 2, Ci: 1}),
+	}
+	urls := []string{"http://dummy/jacoco.xml", "http://dummy/jacoco.xml", "http://dummy/jacoco-it.xml", "http://dummy/jacoco-it.xml"}
+
+	merged := mergeKinds(reports, urls)
+
+	// module-a is fully covered, module-b half, the same source file of both modules is counted twice
+	assert.Equal(t, []report.Counter{{Type: "LINE", Missed: 1, Covered: 3}}, merged.Counters)
+}
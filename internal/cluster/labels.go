@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+)
+
+const (
+	subjectKindLabel  = "subjectkind"
+	pipelineNameLabel = "pipelineName"
+	ownerLabel        = "owner"
+	repositoryLabel   = "repository"
+	branchLabel       = "branch"
+	buildLabel        = "build"
+	contextLabel      = "context"
+	pullRequestLabel  = "pullRequest"
+	statusLabel       = "status"
+)
+
+// factLabels returns the labels of a Fact for the specified pipeline activity. Besides linking the Fact to the
+// activity, the labels allow to select Facts by git owner, repository, branch, build number, pipeline context, pull
+// request number and the status of the pipeline the report came from. All values are converted into valid label
+// values, labels without value are omitted.
+func factLabels(pipelineActivity *jenkinsv1.PipelineActivity) map[string]string {
+	spec := pipelineActivity.Spec
+	labels := map[string]string{
+		subjectKindLabel:  activityKind,
+		pipelineNameLabel: labelValue(pipelineActivity.Name),
+	}
+	optional := map[string]string{
+		ownerLabel:      spec.GitOwner,
+		repositoryLabel: spec.GitRepository,
+		branchLabel:     spec.GitBranch,
+		buildLabel:      spec.Build,
+		contextLabel:    spec.Context,
+		statusLabel:     string(spec.Status),
+	}
+	if pullRequestBranch.MatchString(spec.GitBranch) {
+		optional[pullRequestLabel] = spec.GitBranch[len("PR-"):]
+	}
+	for key, value := range optional {
+		if v := labelValue(value); v != "" {
+			labels[key] = v
+		}
+	}
+	return labels
+}
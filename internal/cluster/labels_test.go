@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestFactLabels(t *testing.T) {
+	activity := &jenkinsv1.PipelineActivity{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "hf-bee-spring-boot-test-pr-6-1"},
+		Spec: jenkinsv1.PipelineActivitySpec{
+			GitOwner:      "hf-bee",
+			GitRepository: "spring-boot-test",
+			GitBranch:     "PR-6",
+			Build:         "1",
+			Context:       "integration tests",
+			Status:        jenkinsv1.ActivityStatusTypeSucceeded,
+		},
+	}
+
+	assert.Equal(t, map[string]string{
+		"subjectkind":  "PipelineActivity",
+		"pipelineName": "hf-bee-spring-boot-test-pr-6-1",
+		"owner":        "hf-bee",
+		"repository":   "spring-boot-test",
+		"branch":       "PR-6",
+		"build":        "1",
+		"context":      "integration-tests",
+		"pullRequest":  "6",
+		"status":       "Succeeded",
+	}, factLabels(activity))
+}
+
+func TestFactLabelsOmitEmptyValues(t *testing.T) {
+	activity := &jenkinsv1.PipelineActivity{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "foo-bar-master-1"},
+		Spec:       jenkinsv1.PipelineActivitySpec{GitBranch: "master", Build: "1"},
+	}
+
+	assert.Equal(t, map[string]string{
+		"subjectkind":  "PipelineActivity",
+		"pipelineName": "foo-bar-master-1",
+		"branch":       "master",
+		"build":        "1",
+	}, factLabels(activity))
+}
+
+func TestLabelValue(t *testing.T) {
+	var testCases = []struct {
+		value    string
+		expected string
+	}{
+		{"master", "master"},
+		{"feature/Foo_Bar", "feature-Foo_Bar"},
+		{"-release 1.0.", "release-1.0"},
+		{"äöü", ""},
+		{strings.Repeat("a", 70), strings.Repeat("a", 63)},
+		{strings.Repeat("a", 62) + "/b", strings.Repeat("a", 62)},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, labelValue(testCase.value), testCase.value)
+	}
+}
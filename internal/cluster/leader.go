@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/logging"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"os"
+	"time"
+)
+
+const (
+	// leaseName is the name of the Lease held by the replica processing pipeline activities.
+	leaseName = logging.AppName
+)
+
+// RunAsLeader runs the specified function once this replica acquired the leader lease in the namespace of the
+// configuration. The done channel passed to the function is closed when the lease is lost or the specified done
+// channel is closed. RunAsLeader returns after the function returned. If the function returns although the done
+// channel has not been closed, the process exits so that another replica takes over.
+func RunAsLeader(kubeClient kubernetes.Interface, config config.Configuration, run func(done chan struct{}), done chan struct{}) {
+	identity := config.LeaderIdentity()
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	lock := &leaseLock{
+		namespace: config.Namespace(),
+		name:      leaseName,
+		identity:  identity,
+		client:    kubeClient.CoordinationV1beta1(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	leaseDuration, renewDeadline, retryPeriod := leaseTimings(config.LeaseDuration())
+	leading := make(chan context.Context, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:          lock,
+			LeaseDuration: leaseDuration,
+			RenewDeadline: renewDeadline,
+			RetryPeriod:   retryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leaderCtx context.Context) {
+					leading <- leaderCtx
+				},
+				OnStoppedLeading: func() {
+					logger.Infof("%s stopped leading %s", identity, lock.Describe())
+				},
+				OnNewLeader: func(leader string) {
+					logger.Infof("%s is the leader of %s", leader, lock.Describe())
+				},
+			},
+		})
+	}()
+
+	logger.Infof("%s waiting for leader lease %s", identity, lock.Describe())
+	select {
+	case <-finished:
+	case leaderCtx := <-leading:
+		select {
+		case <-done:
+			// the lease was acquired while shutting down
+		default:
+			logger.Infof("%s acquired leader lease %s", identity, lock.Describe())
+			leaderDone := make(chan struct{})
+			go func() {
+				<-leaderCtx.Done()
+				close(leaderDone)
+			}()
+			run(leaderDone)
+		}
+		cancel()
+		<-finished
+	}
+
+	select {
+	case <-done:
+	default:
+		logger.Fatalf("%s is no longer leading %s, exiting", identity, lock.Describe())
+	}
+}
+
+// leaseTimings returns the lease duration, renew deadline and retry period of the leader election for the specified
+// lease duration in seconds, using the same ratios as the Kubernetes controller manager.
+func leaseTimings(leaseDurationSeconds int) (time.Duration, time.Duration, time.Duration) {
+	leaseDuration := time.Duration(leaseDurationSeconds) * time.Second
+	return leaseDuration, leaseDuration * 2 / 3, leaseDuration * 2 / 15
+}
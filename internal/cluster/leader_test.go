@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"testing"
+	"time"
+)
+
+func TestRunAsLeader(t *testing.T) {
+	done := make(chan struct{})
+	var leaderDone chan struct{}
+	run := func(d chan struct{}) {
+		leaderDone = d
+		close(done)
+		<-d
+	}
+
+	RunAsLeader(fake.NewSimpleClientset(), &testConfig{leaseDuration: 1, leaderIdentity: "jacoco-0"}, run, done)
+
+	assert.NotNil(t, leaderDone, "the function should have been run once the lease was acquired")
+	_, open := <-leaderDone
+	assert.False(t, open, "the done channel of the leader should be closed on shutdown")
+}
+
+func TestRunAsLeaderShutdownWhileWaiting(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	run := func(d chan struct{}) {
+		assert.Fail(t, "the function should not run after shutdown")
+	}
+
+	RunAsLeader(fake.NewSimpleClientset(), &testConfig{leaseDuration: 1}, run, done)
+}
+
+func TestLeaseSpecConversion(t *testing.T) {
+	now := time.Now().Truncate(time.Microsecond)
+	record := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       "jacoco-0",
+		LeaseDurationSeconds: 15,
+		AcquireTime:          metav1.Time{Time: now.Add(-time.Minute)},
+		RenewTime:            metav1.Time{Time: now},
+		LeaderTransitions:    3,
+	}
+
+	spec := leaseSpec(record)
+
+	assert.Equal(t, "jacoco-0", *spec.HolderIdentity)
+	assert.Equal(t, int32(15), *spec.LeaseDurationSeconds)
+	assert.Equal(t, int32(3), *spec.LeaseTransitions)
+	assert.Equal(t, &record, leaderElectionRecord(spec))
+}
+
+func TestLeaseTimings(t *testing.T) {
+	leaseDuration, renewDeadline, retryPeriod := leaseTimings(15)
+
+	assert.Equal(t, 15*time.Second, leaseDuration)
+	assert.Equal(t, 10*time.Second, renewDeadline)
+	assert.Equal(t, 2*time.Second, retryPeriod)
+}
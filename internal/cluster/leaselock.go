@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1beta1client "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaseLock is a resourcelock.Interface which stores the leader election record in a coordination.k8s.io Lease.
+type leaseLock struct {
+	namespace string
+	name      string
+	identity  string
+	client    coordinationv1beta1client.LeasesGetter
+	lease     *coordinationv1beta1.Lease
+}
+
+// Get returns the leader election record stored in the Lease.
+func (l *leaseLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	lease, err := l.client.Leases(l.namespace).Get(l.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	l.lease = lease
+	return leaderElectionRecord(lease.Spec), nil
+}
+
+// Create creates the Lease with the specified leader election record.
+func (l *leaseLock) Create(record resourcelock.LeaderElectionRecord) error {
+	lease, err := l.client.Leases(l.namespace).Create(&coordinationv1beta1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      l.name,
+			Namespace: l.namespace,
+		},
+		Spec: leaseSpec(record),
+	})
+	if err != nil {
+		return err
+	}
+	l.lease = lease
+	return nil
+}
+
+// Update updates the Lease with the specified leader election record.
+func (l *leaseLock) Update(record resourcelock.LeaderElectionRecord) error {
+	if l.lease == nil {
+		return errors.New("lease not initialized, call get or create first")
+	}
+	l.lease.Spec = leaseSpec(record)
+	lease, err := l.client.Leases(l.namespace).Update(l.lease)
+	if err != nil {
+		return err
+	}
+	l.lease = lease
+	return nil
+}
+
+// RecordEvent does nothing, leader changes are logged instead.
+func (l *leaseLock) RecordEvent(string) {
+}
+
+// Identity returns the identity of this replica.
+func (l *leaseLock) Identity() string {
+	return l.identity
+}
+
+// Describe returns the namespace and name of the Lease.
+func (l *leaseLock) Describe() string {
+	return fmt.Sprintf("%s/%s", l.namespace, l.name)
+}
+
+// leaseSpec converts a leader election record to the spec of a Lease.
+func leaseSpec(record resourcelock.LeaderElectionRecord) coordinationv1beta1.LeaseSpec {
+	holderIdentity := record.HolderIdentity
+	leaseDurationSeconds := int32(record.LeaseDurationSeconds)
+	leaseTransitions := int32(record.LeaderTransitions)
+	return coordinationv1beta1.LeaseSpec{
+		HolderIdentity:       &holderIdentity,
+		LeaseDurationSeconds: &leaseDurationSeconds,
+		AcquireTime:          &metav1.MicroTime{Time: record.AcquireTime.Time},
+		RenewTime:            &metav1.MicroTime{Time: record.RenewTime.Time},
+		LeaseTransitions:     &leaseTransitions,
+	}
+}
+
+// leaderElectionRecord converts the spec of a Lease to a leader election record.
+func leaderElectionRecord(spec coordinationv1beta1.LeaseSpec) *resourcelock.LeaderElectionRecord {
+	record := resourcelock.LeaderElectionRecord{}
+	if spec.HolderIdentity != nil {
+		record.HolderIdentity = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		record.LeaseDurationSeconds = int(*spec.LeaseDurationSeconds)
+	}
+	if spec.AcquireTime != nil {
+		record.AcquireTime = metav1.Time{Time: spec.AcquireTime.Time}
+	}
+	if spec.RenewTime != nil {
+		record.RenewTime = metav1.Time{Time: spec.RenewTime.Time}
+	}
+	if spec.LeaseTransitions != nil {
+		record.LeaderTransitions = int(*spec.LeaseTransitions)
+	}
+	return &record
+}
@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/metrics"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"strconv"
+)
+
+// recordCoverage sets the coverage gauges of the repository branch of the specified Fact from its totals. Breakdown and
+// module measurements of the Fact are ignored. No gauges are exported for pull requests, since their branches are
+// short-lived and would add series without bound.
+func recordCoverage(fact *jenkinsv1.Fact) {
+	series, build, ok := coverageSeries(fact)
+	if !ok {
+		return
+	}
+	for _, coverage := range FactCoverage(fact) {
+		metrics.SetCoverage(series, build, coverage.Type, coverage.Covered, coverage.Covered+coverage.Missed)
+	}
+	logger.Tracef("recorded coverage of %+v", series)
+}
+
+// forgetCoverage deletes the coverage gauges of the repository branch of the specified deleted Fact, if they were set
+// from the build of the Fact.
+func forgetCoverage(fact *jenkinsv1.Fact) {
+	series, build, ok := coverageSeries(fact)
+	if !ok {
+		return
+	}
+	metrics.DeleteCoverage(series, build)
+	logger.Tracef("deleted coverage of %+v", series)
+}
+
+// coverageSeries returns the series of the coverage gauges of the specified Fact and its build number, taken from the
+// labels of the Fact. It returns false for Facts of pull requests.
+func coverageSeries(fact *jenkinsv1.Fact) (metrics.CoverageSeries, int, bool) {
+	if fact.Labels[pullRequestLabel] != "" || pullRequestBranch.MatchString(fact.Labels[branchLabel]) {
+		return metrics.CoverageSeries{}, 0, false
+	}
+	series := metrics.CoverageSeries{
+		Namespace:  fact.Namespace,
+		Owner:      fact.Labels[ownerLabel],
+		Repository: fact.Labels[repositoryLabel],
+		Branch:     fact.Labels[branchLabel],
+		Kind:       fact.Labels[coverageKindLabel],
+		Module:     fact.Labels[moduleTag],
+	}
+	build, _ := strconv.Atoi(fact.Labels[buildLabel])
+	return series, build, true
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/metrics"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http/httptest"
+	"testing"
+)
+
+func metricsFact(branch string, build string) *jenkinsv1.Fact {
+	activity := &jenkinsv1.PipelineActivity{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "jx", Name: "metrics-repo-" + branch + "-" + build},
+		Spec:       jenkinsv1.PipelineActivitySpec{GitOwner: "metrics", GitRepository: "repo", GitBranch: branch, Build: build},
+	}
+	labels := factLabels(activity)
+	labels[coverageKindLabel] = kindUnit
+	return &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "jx", Labels: labels},
+		Spec: jenkinsv1.FactSpec{FactType: jenkinsv1.FactTypeCoverage, Tags: []string{appName}, Measurements: []jenkinsv1.Measurement{
+			{Name: "Lines-Covered", MeasurementValue: 3},
+			{Name: "Lines-Missed", MeasurementValue: 1},
+			{Name: "Lines-Total", MeasurementValue: 4},
+			{Name: "Lines-Percent", MeasurementValue: 75},
+			{Name: "com.example/Lines-Covered", MeasurementValue: 0, Tags: []string{"package"}},
+			{Name: "com.example/Lines-Total", MeasurementValue: 4, Tags: []string{"package"}},
+		}},
+	}
+}
+
+func scrapeMetrics() string {
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	return recorder.Body.String()
+}
+
+func TestRecordCoverage(t *testing.T) {
+	recordCoverage(metricsFact("master", "7"))
+
+	assert.Contains(t, scrapeMetrics(), `jacoco_coverage_ratio{branch="master",counter="lines",kind="unit",module="",namespace="jx",owner="metrics",repository="repo"} 0.75`)
+}
+
+func TestRecordCoverageIgnoresPullRequests(t *testing.T) {
+	recordCoverage(metricsFact("PR-12", "1"))
+
+	assert.NotContains(t, scrapeMetrics(), `branch="PR-12"`)
+}
+
+func TestForgetCoverage(t *testing.T) {
+	fact := metricsFact("release", "3")
+	recordCoverage(fact)
+	assert.Contains(t, scrapeMetrics(), `branch="release"`)
+
+	forgetCoverage(fact)
+	assert.NotContains(t, scrapeMetrics(), `branch="release"`)
+}
+
+func TestSeedRecordsCoverage(t *testing.T) {
+	latest := metricsFact("seeded", "5")
+	older := metricsFact("seeded", "4")
+	older.Spec.Measurements = []jenkinsv1.Measurement{{Name: "Lines-Covered", MeasurementValue: 1}, {Name: "Lines-Missed", MeasurementValue: 3}}
+
+	_, err := newProcessedCache().seed(&listingFactInterface{facts: []jenkinsv1.Fact{*latest, *older}})
+	assert.NoError(t, err)
+
+	assert.Contains(t, scrapeMetrics(), `jacoco_coverage_ratio{branch="seeded",counter="lines",kind="unit",module="",namespace="jx",owner="metrics",repository="repo"} 0.75`)
+}
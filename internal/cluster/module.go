@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"hash/fnv"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxLabelValueLength = 63
+
+	// maxNameLength is the maximum length of a Kubernetes resource name.
+	maxNameLength = 253
+
+	// nameHashLength is the length of the hash appended to truncated resource names.
+	nameHashLength = 8
+)
+
+var (
+	// buildOutputDirs are the names of build output directories, the directory above is the module directory.
+	buildOutputDirs = []string{"target", "build"}
+
+	invalidNameChars       = regexp.MustCompile(`[^a-z0-9.-]+`)
+	invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// moduleIDs returns a module identifier for each of the specified reports. Identifiers which would otherwise not be
+// unique get the position of the report appended.
+func moduleIDs(reports []report.Report, urls []string) []string {
+	ids := make([]string, len(reports))
+	seen := map[string]int{}
+	for i, r := range reports {
+		id := moduleID(r, urls[i])
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// moduleID returns a stable identifier for the module the specified report belongs to. The identifier is derived from
+// the report name or, if the report has no name, from the URL path of the report. The identifier is a valid part of
+// a Kubernetes resource name.
+func moduleID(coverageReport report.Report, reportURL string) string {
+	id := sanitizeName(coverageReport.Name)
+	if id == "" {
+		id = sanitizeName(modulePath(reportURL))
+	}
+	if id == "" {
+		id = "default"
+	}
+	return id
+}
+
+// modulePath returns the name of the module directory of the specified report URL, which is the directory containing
+// the build output directory, eg 'module-a' for '.../module-a/target/site/jacoco/jacoco.xml'. If the URL contains no
+// build output directory, the name of the directory containing the report is returned.
+func modulePath(reportURL string) string {
+	p := reportURL
+	if u, err := url.Parse(reportURL); err == nil {
+		p = u.Path
+	}
+
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		for _, dir := range buildOutputDirs {
+			if segments[i] == dir {
+				return segments[i-1]
+			}
+		}
+	}
+	return path.Base(path.Dir(p))
+}
+
+// sanitizeName converts the specified string into a valid part of a Kubernetes resource name.
+func sanitizeName(s string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+	return strings.Trim(name, "-.")
+}
+
+// truncateName truncates the specified resource name to the maximum resource name length. A truncated name ends with a
+// hash of the complete name, so that names with the same prefix stay unique and the same name is always truncated the
+// same way.
+func truncateName(name string) string {
+	if len(name) <= maxNameLength {
+		return name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	prefix := strings.TrimRight(name[:maxNameLength-nameHashLength-1], "-.")
+	return fmt.Sprintf("%s-%0*x", prefix, nameHashLength, hash.Sum32())
+}
+
+// labelValue converts the specified string into a valid Kubernetes label value. Invalid characters are replaced by
+// dashes, the value is truncated to the maximum label value length and non-alphanumeric characters at both ends are
+// removed.
+func labelValue(s string) string {
+	value := invalidLabelValueChars.ReplaceAllString(s, "-")
+	if len(value) > maxLabelValueLength {
+		value = value[:maxLabelValueLength]
+	}
+	return strings.Trim(value, "-._")
+}
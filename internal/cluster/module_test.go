@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestModuleID(t *testing.T) {
+	var testCases = []struct {
+		reportName string
+		url        string
+		expectedID string
+	}{
+		{"Module A", "http://dummy/jacoco.xml", "module-a"},
+		{"", "https://raw.githubusercontent.com/org/repo/gh-pages/jenkins-x/jacoco/org/repo/PR-6/1/module-b/target/site/jacoco/jacoco.xml", "module-b"},
+		{"", "gs://bucket/org/repo/master/3/web/build/reports/cobertura.xml", "web"},
+		{"", "http://dummy/coverage/lcov.info?version=1", "coverage"},
+		{"", "lcov.info", "default"},
+	}
+
+	for _, testCase := range testCases {
+		id := moduleID(report.Report{Name: testCase.reportName}, testCase.url)
+		assert.Equal(t, testCase.expectedID, id, testCase.url)
+	}
+}
+
+func TestModuleIDsAreUnique(t *testing.T) {
+	reports := []report.Report{{Name: "demo"}, {Name: "demo"}, {Name: "other"}}
+	urls := []string{"http://dummy/a/jacoco.xml", "http://dummy/b/jacoco.xml", "http://dummy/c/jacoco.xml"}
+
+	assert.Equal(t, []string{"demo", "demo-2", "other"}, moduleIDs(reports, urls))
+}
+
+func TestTruncateName(t *testing.T) {
+	assert.Equal(t, "jacoco-jx.coverage-foo-module-a", truncateName("jacoco-jx.coverage-foo-module-a"))
+
+	long := "jacoco-jx.coverage-foo-" + strings.Repeat("module-", 40)
+	truncated := truncateName(long + "a")
+	assert.Len(t, truncated, maxNameLength)
+	assert.Equal(t, truncated, truncateName(long+"a"), "truncation should be stable")
+	assert.NotEqual(t, truncated, truncateName(long+"b"), "truncated names should stay unique")
+	assert.True(t, strings.HasPrefix(truncated, long[:200]))
+}
@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchNamespaces returns the namespaces whose pipeline activities are watched. config.AllNamespaces is mapped to
+// metav1.NamespaceAll.
+func watchNamespaces(jxConfig config.JXConfig) []string {
+	var namespaces []string
+	for _, namespace := range jxConfig.WatchNamespaces() {
+		if namespace == config.AllNamespaces {
+			return []string{metav1.NamespaceAll}
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
+
+// describeNamespace returns a description of the specified namespace for log messages.
+func describeNamespace(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return "all namespaces"
+	}
+	return fmt.Sprintf("namespace '%s'", namespace)
+}
+
+// indexer returns the indexer of the pipeline activities in the specified namespace, or the indexer of all namespaces
+// if all namespaces are watched. It returns nil if the namespace is not watched.
+func (h *defaultEventHandler) indexer(namespace string) cache.Indexer {
+	if indexer, ok := h.indexers[namespace]; ok {
+		return indexer
+	}
+	return h.indexers[metav1.NamespaceAll]
+}
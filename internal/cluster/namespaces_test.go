@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsclientv1 "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"testing"
+)
+
+// namespacedFactInterface is a listingFactInterface recording the namespaces Facts are accessed in.
+type namespacedFactInterface struct {
+	listingFactInterface
+	namespaces []string
+}
+
+func (m *namespacedFactInterface) Facts(namespace string) jenkinsclientv1.FactInterface {
+	m.namespaces = append(m.namespaces, namespace)
+	return &m.listingFactInterface
+}
+
+func TestWatchNamespaces(t *testing.T) {
+	assert.Equal(t, []string{"jx"}, watchNamespaces(&testConfig{}))
+	assert.Equal(t, []string{"team-a", "team-b"}, watchNamespaces(&testConfig{watchNamespaces: []string{"team-a", "team-b"}}))
+	assert.Equal(t, []string{meta_v1.NamespaceAll}, watchNamespaces(&testConfig{watchNamespaces: []string{"*"}}))
+}
+
+func TestIndexer(t *testing.T) {
+	teamA := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	handler := defaultEventHandler{indexers: map[string]cache.Indexer{"team-a": teamA}}
+	assert.Equal(t, teamA, handler.indexer("team-a"))
+	assert.Nil(t, handler.indexer("team-b"))
+
+	all := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	handler = defaultEventHandler{indexers: map[string]cache.Indexer{meta_v1.NamespaceAll: all}}
+	assert.Equal(t, all, handler.indexer("team-b"))
+}
+
+func TestSyncDeletesFactsInActivityNamespace(t *testing.T) {
+	fact := coverageFact("jacoco-jx.coverage-foo-bar-master-1", "foo-bar-master-1", "uid-1")
+	fact.Namespace = "team-a"
+	factsInterface := &namespacedFactInterface{listingFactInterface: listingFactInterface{facts: []jenkinsv1.Fact{fact}}}
+	handler := defaultEventHandler{
+		facts:    factsInterface,
+		indexers: map[string]cache.Indexer{meta_v1.NamespaceAll: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})},
+	}
+
+	err := handler.sync("team-a/foo-bar-master-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-a"}, factsInterface.namespaces, "facts should be listed and deleted in the activity namespace")
+	assert.Equal(t, []string{"jacoco-jx.coverage-foo-bar-master-1"}, factsInterface.deleted)
+}
+
+func TestSyncRejectsUnwatchedNamespace(t *testing.T) {
+	handler := defaultEventHandler{indexers: map[string]cache.Indexer{"team-a": cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})}}
+
+	assert.Error(t, handler.sync("team-b/foo-bar-master-1"))
+}
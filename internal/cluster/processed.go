@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsv1types "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// attachmentAnnotation is the annotation of a Fact containing the name of the attachment the Fact was created from.
+	attachmentAnnotation = "jacoco.jenkins-x.io/attachment"
+
+	// fingerprintAnnotation is the annotation of a Fact containing the fingerprint of the attachment the Fact was created from.
+	fingerprintAnnotation = "jacoco.jenkins-x.io/attachment-fingerprint"
+)
+
+// processedCache keeps track of the attachments of pipeline activities which have been processed successfully,
+// so that unchanged attachments are not processed again on each resync of the informer.
+type processedCache struct {
+	sync.Mutex
+	// fingerprints maps the UID of an activity to the fingerprints of its processed attachments, keyed by attachment name
+	fingerprints map[types.UID]map[string]string
+}
+
+func newProcessedCache() *processedCache {
+	return &processedCache{fingerprints: map[types.UID]map[string]string{}}
+}
+
+// isProcessed returns true if the specified attachment of the activity with the specified UID has been processed
+// and its URLs have not changed since.
+func (c *processedCache) isProcessed(uid types.UID, attachment jenkinsv1.Attachment) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	fingerprint, ok := c.fingerprints[uid][attachment.Name]
+	return ok && fingerprint == attachmentFingerprint(attachment)
+}
+
+// markProcessed records that the attachment with the specified name and fingerprint has been processed.
+func (c *processedCache) markProcessed(uid types.UID, attachmentName string, fingerprint string) {
+	c.Lock()
+	defer c.Unlock()
+
+	attachments, ok := c.fingerprints[uid]
+	if !ok {
+		attachments = map[string]string{}
+		c.fingerprints[uid] = attachments
+	}
+	attachments[attachmentName] = fingerprint
+}
+
+// forget removes all entries of the activity with the specified UID.
+func (c *processedCache) forget(uid types.UID) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.fingerprints, uid)
+}
+
+// seed adds the attachments recorded in the annotations of the existing Facts to the cache. The coverage gauges are
+// restored from the existing coverage Facts as well, so that they are not empty until each repository builds again.
+// It returns the number of Facts the cache was seeded from.
+func (c *processedCache) seed(factsInterface jenkinsv1types.FactInterface) (int, error) {
+	facts, err := factsInterface.List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", subjectKindLabel, activityKind)})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i, fact := range facts.Items {
+		if isCoverageFact(fact) {
+			recordCoverage(&facts.Items[i])
+		}
+		attachmentName, ok := fact.Annotations[attachmentAnnotation]
+		if !ok || fact.Spec.SubjectReference.UID == "" {
+			continue
+		}
+		c.markProcessed(fact.Spec.SubjectReference.UID, attachmentName, fact.Annotations[fingerprintAnnotation])
+		count++
+	}
+	return count, nil
+}
+
+// attachmentFingerprint returns a fingerprint of the URL set of the specified attachment.
+func attachmentFingerprint(attachment jenkinsv1.Attachment) string {
+	urls := append([]string{}, attachment.URLs...)
+	sort.Strings(urls)
+	hash := sha256.Sum256([]byte(strings.Join(urls, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// annotateFact records the attachment the specified Fact was created from in the annotations of the Fact.
+func annotateFact(fact *jenkinsv1.Fact, attachment jenkinsv1.Attachment) {
+	if fact.Annotations == nil {
+		fact.Annotations = map[string]string{}
+	}
+	fact.Annotations[attachmentAnnotation] = attachment.Name
+	fact.Annotations[fingerprintAnnotation] = attachmentFingerprint(attachment)
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsclientv1 "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"strconv"
+	"testing"
+)
+
+// listingFactInterface is a FactInterface listing a fixed set of Facts and recording the names of deleted Facts.
+// It ignores the namespaces of the Facts and pages the listed Facts if a limit is requested.
+type listingFactInterface struct {
+	mockFactInterface
+	facts     []jenkinsv1.Fact
+	deleted   []string
+	listCount int
+}
+
+func (m *listingFactInterface) Facts(namespace string) jenkinsclientv1.FactInterface {
+	return m
+}
+
+func (m *listingFactInterface) List(opts meta_v1.ListOptions) (*jenkinsv1.FactList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &jenkinsv1.FactList{}
+	for _, fact := range m.facts {
+		if selector.Matches(labels.Set(fact.Labels)) {
+			list.Items = append(list.Items, fact)
+		}
+	}
+	m.listCount++
+	if opts.Limit <= 0 {
+		return list, nil
+	}
+	start, _ := strconv.Atoi(opts.Continue)
+	end := start + int(opts.Limit)
+	if end < len(list.Items) {
+		list.Continue = strconv.Itoa(end)
+	} else {
+		end = len(list.Items)
+	}
+	list.Items = list.Items[start:end]
+	return list, nil
+}
+
+func (m *listingFactInterface) Delete(name string, options *meta_v1.DeleteOptions) error {
+	m.deleted = append(m.deleted, name)
+	return nil
+}
+
+func TestProcessedCache(t *testing.T) {
+	attachment := jenkinsv1.Attachment{Name: "jacoco", URLs: []string{"http://dummy/b.xml", "http://dummy/a.xml"}}
+	cache := newProcessedCache()
+	assert.False(t, cache.isProcessed("uid", attachment))
+
+	cache.markProcessed("uid", attachment.Name, attachmentFingerprint(attachment))
+	assert.True(t, cache.isProcessed("uid", attachment))
+
+	reordered := jenkinsv1.Attachment{Name: "jacoco", URLs: []string{"http://dummy/a.xml", "http://dummy/b.xml"}}
+	assert.True(t, cache.isProcessed("uid", reordered))
+
+	changed := jenkinsv1.Attachment{Name: "jacoco", URLs: []string{"http://dummy/a.xml"}}
+	assert.False(t, cache.isProcessed("uid", changed))
+	assert.False(t, cache.isProcessed("other-uid", attachment))
+
+	cache.forget("uid")
+	assert.False(t, cache.isProcessed("uid", attachment))
+}
+
+func TestProcessedCacheSeed(t *testing.T) {
+	attachment := jenkinsv1.Attachment{Name: "jacoco", URLs: []string{"http://dummy/jacoco.xml"}}
+	annotatedFact := jenkinsv1.Fact{ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"subjectkind": "PipelineActivity"}}, Spec: jenkinsv1.FactSpec{SubjectReference: jenkinsv1.ResourceReference{UID: "uid"}}}
+	annotateFact(&annotatedFact, attachment)
+	otherFact := jenkinsv1.Fact{ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"subjectkind": "PipelineActivity"}}, Spec: jenkinsv1.FactSpec{SubjectReference: jenkinsv1.ResourceReference{UID: "other-uid"}}}
+
+	cache := newProcessedCache()
+	count, err := cache.seed(&listingFactInterface{facts: []jenkinsv1.Fact{annotatedFact, otherFact}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.True(t, cache.isProcessed("uid", attachment))
+	assert.False(t, cache.isProcessed("other-uid", attachment))
+}
+
+func TestOverwrittenFactsOfProcessedAttachment(t *testing.T) {
+	attachment := jenkinsv1.Attachment{Name: "jacoco", URLs: []string{"http://dummy/jacoco.xml"}}
+	pipelineActivity := &jenkinsv1.PipelineActivity{ObjectMeta: meta_v1.ObjectMeta{Name: "foo-bar-master-1", UID: "uid"}}
+	linesCovered := func(covered int) *jenkinsv1.Fact {
+		measurements := []jenkinsv1.Measurement{{Name: "Lines-Covered", MeasurementType: jenkinsv1.MeasurementCount, MeasurementValue: covered}}
+		return &jenkinsv1.Fact{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "foo-bar-coverage", ResourceVersion: "42"},
+			Spec:       jenkinsv1.FactSpec{Original: jenkinsv1.Original{URL: "http://dummy/jacoco.xml"}, Measurements: measurements},
+		}
+	}
+	existing := linesCovered(1)
+	annotateFact(existing, attachment)
+	factsInterface := &existingFactInterface{existing: existing}
+	config := &testConfig{overwriteFacts: true}
+	handler := defaultEventHandler{config: config, facts: factsInterface, processed: newProcessedCache()}
+
+	assert.NoError(t, handler.storeFacts([]*jenkinsv1.Fact{linesCovered(1)}, pipelineActivity, attachment))
+	assert.Equal(t, 0, factsInterface.updateCount, "the unchanged Fact should not be updated")
+	assert.False(t, handler.isProcessed(pipelineActivity, attachment), "the attachment should be processed again if Facts are overwritten")
+
+	// the report is replaced at the same URL
+	replaced := linesCovered(2)
+	assert.NoError(t, handler.storeFacts([]*jenkinsv1.Fact{replaced}, pipelineActivity, attachment))
+	assert.Equal(t, 1, factsInterface.updateCount)
+	assert.Equal(t, replaced.Spec.Measurements, factsInterface.updated.Spec.Measurements)
+
+	config.overwriteFacts = false
+	assert.True(t, handler.isProcessed(pipelineActivity, attachment))
+}
@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/config"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	jenkinsv1types "github.com/jenkins-x/jx/pkg/client/clientset/versioned/typed/jenkins.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"strings"
+)
+
+const (
+	// factPageSize is the number of Facts requested per list call when querying coverage Facts.
+	factPageSize = 100
+)
+
+// CoverageQuery selects the coverage Facts of a repository branch. Empty fields match all Facts.
+type CoverageQuery struct {
+	Owner      string
+	Repository string
+	Branch     string
+	Build      string
+}
+
+// Coverage is the coverage of a counter type, eg Lines, stored in a Fact.
+type Coverage struct {
+	Type    string
+	Covered int
+	Missed  int
+}
+
+// CoverageFact is a coverage Fact together with the build details from its labels. The measurements and statements of
+// the Fact are dropped once its coverage is read, so that listing Facts with large breakdowns does not retain them.
+type CoverageFact struct {
+	Fact       *jenkinsv1.Fact
+	Owner      string
+	Repository string
+	Branch     string
+	// Build is the build number, or 0 if the build is not a number
+	Build    int
+	Kind     string
+	Module   string
+	Coverage []Coverage
+}
+
+// ListCoverageFacts lists the coverage Facts matching the specified query in the watched namespaces. The Facts are
+// listed in pages of factPageSize Facts.
+func ListCoverageFacts(factsGetter jenkinsv1types.FactsGetter, jxConfig config.JXConfig, query CoverageQuery) ([]CoverageFact, error) {
+	selector := []string{fmt.Sprintf("%s=%s", subjectKindLabel, activityKind)}
+	for label, value := range map[string]string{
+		ownerLabel:      query.Owner,
+		repositoryLabel: query.Repository,
+		branchLabel:     query.Branch,
+		buildLabel:      query.Build,
+	} {
+		if value != "" {
+			selector = append(selector, fmt.Sprintf("%s=%s", label, labelValue(value)))
+		}
+	}
+
+	var coverageFacts []CoverageFact
+	for _, namespace := range watchNamespaces(jxConfig) {
+		factsInterface := factsGetter.Facts(namespace)
+		options := metav1.ListOptions{LabelSelector: strings.Join(selector, ","), Limit: factPageSize}
+		for {
+			facts, err := factsInterface.List(options)
+			if err != nil {
+				return nil, err
+			}
+			for _, fact := range facts.Items {
+				if isCoverageFact(fact) {
+					coverageFacts = append(coverageFacts, newCoverageFact(fact))
+				}
+			}
+			if facts.Continue == "" {
+				break
+			}
+			options.Continue = facts.Continue
+		}
+	}
+	return coverageFacts, nil
+}
+
+// newCoverageFact returns the coverage of the specified Fact together with the Fact without its measurements and
+// statements.
+func newCoverageFact(fact jenkinsv1.Fact) CoverageFact {
+	coverage := FactCoverage(&fact)
+	fact.Spec.Measurements = nil
+	fact.Spec.Statements = nil
+	build, _ := strconv.Atoi(fact.Labels[buildLabel])
+	return CoverageFact{
+		Fact:       &fact,
+		Owner:      fact.Labels[ownerLabel],
+		Repository: fact.Labels[repositoryLabel],
+		Branch:     fact.Labels[branchLabel],
+		Build:      build,
+		Kind:       fact.Labels[coverageKindLabel],
+		Module:     fact.Labels[moduleTag],
+		Coverage:   coverage,
+	}
+}
+
+// FactCoverage returns the coverage of each counter type of the report totals stored in the specified Fact, in the
+// order of the measurements. Breakdown and module measurements are ignored.
+func FactCoverage(fact *jenkinsv1.Fact) []Coverage {
+	var coverage []Coverage
+	index := map[string]int{}
+	for _, measurement := range fact.Spec.Measurements {
+		if len(measurement.Tags) > 0 {
+			continue
+		}
+		separator := strings.LastIndex(measurement.Name, "-")
+		if separator < 0 {
+			continue
+		}
+		counterType, measurementName := measurement.Name[:separator], measurement.Name[separator+1:]
+		if measurementName != jenkinsv1.CodeCoverageMeasurementCoverage && measurementName != jenkinsv1.CodeCoverageMeasurementMissed {
+			continue
+		}
+		i, ok := index[counterType]
+		if !ok {
+			i = len(coverage)
+			index[counterType] = i
+			coverage = append(coverage, Coverage{Type: counterType})
+		}
+		if measurementName == jenkinsv1.CodeCoverageMeasurementCoverage {
+			coverage[i].Covered = measurement.MeasurementValue
+		} else {
+			coverage[i].Missed = measurement.MeasurementValue
+		}
+	}
+	return coverage
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"fmt"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"testing"
+)
+
+func buildFact(name string, branch string, build string, kind string) jenkinsv1.Fact {
+	fact := coverageFact(name, "foo-bar-"+branch+"-"+build, "uid")
+	fact.Labels[ownerLabel] = "foo"
+	fact.Labels[repositoryLabel] = "bar"
+	fact.Labels[branchLabel] = branch
+	fact.Labels[buildLabel] = build
+	fact.Labels[coverageKindLabel] = kind
+	return fact
+}
+
+func TestListCoverageFacts(t *testing.T) {
+	otherFact := buildFact("other", "master", "1", kindUnit)
+	otherFact.Spec.FactType = "other"
+	factsInterface := &namespacedFactInterface{listingFactInterface: listingFactInterface{facts: []jenkinsv1.Fact{
+		buildFact("master-1", "master", "1", kindUnit),
+		buildFact("master-2", "master", "2", kindIntegration),
+		buildFact("feature-1", "feature-x", "1", kindUnit),
+		otherFact,
+	}}}
+
+	facts, err := ListCoverageFacts(factsInterface, &testConfig{watchNamespaces: []string{"team-a", "team-b"}}, CoverageQuery{Owner: "foo", Repository: "bar", Branch: "master"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-b"}, factsInterface.namespaces)
+	assert.Len(t, facts, 4, "facts of both namespaces should be listed")
+	assert.Equal(t, "master-1", facts[0].Fact.Name)
+	assert.Equal(t, 1, facts[0].Build)
+	assert.Equal(t, kindUnit, facts[0].Kind)
+	assert.Equal(t, "master-2", facts[1].Fact.Name)
+	assert.Equal(t, 2, facts[1].Build)
+	assert.Equal(t, kindIntegration, facts[1].Kind)
+
+	facts, err = ListCoverageFacts(&listingFactInterface{facts: factsInterface.facts}, &testConfig{}, CoverageQuery{Owner: "foo", Repository: "bar", Branch: "master", Build: "2"})
+
+	assert.NoError(t, err)
+	assert.Len(t, facts, 1)
+	assert.Equal(t, "master-2", facts[0].Fact.Name)
+}
+
+func TestListCoverageFactsInPages(t *testing.T) {
+	var facts []jenkinsv1.Fact
+	for i := 1; i <= factPageSize*2+1; i++ {
+		fact := buildFact(fmt.Sprintf("master-%d", i), "master", strconv.Itoa(i), kindUnit)
+		fact.Spec.Measurements = []jenkinsv1.Measurement{
+			{Name: "Lines-Covered", MeasurementValue: 1},
+			{Name: "Lines-Missed", MeasurementValue: 1},
+			{Name: "org/example/Lines-Covered", MeasurementValue: 1, Tags: []string{packageTag}},
+		}
+		facts = append(facts, fact)
+	}
+	factsInterface := &listingFactInterface{facts: facts}
+
+	coverageFacts, err := ListCoverageFacts(factsInterface, &testConfig{}, CoverageQuery{Owner: "foo", Repository: "bar"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, factsInterface.listCount)
+	assert.Len(t, coverageFacts, factPageSize*2+1)
+	last := coverageFacts[len(coverageFacts)-1]
+	assert.Equal(t, fmt.Sprintf("master-%d", factPageSize*2+1), last.Fact.Name)
+	assert.Equal(t, []Coverage{{Type: "Lines", Covered: 1, Missed: 1}}, last.Coverage)
+	assert.Empty(t, last.Fact.Spec.Measurements, "measurements should not be retained")
+}
+
+func TestFactCoverage(t *testing.T) {
+	fact := &jenkinsv1.Fact{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "fact"},
+		Spec: jenkinsv1.FactSpec{Measurements: []jenkinsv1.Measurement{
+			{Name: "Lines-Covered", MeasurementValue: 3},
+			{Name: "Lines-Missed", MeasurementValue: 1},
+			{Name: "Lines-Total", MeasurementValue: 4},
+			{Name: "Lines-Percent", MeasurementValue: 75},
+			{Name: "Branches-Missed", MeasurementValue: 2},
+			{Name: "Branches-Covered", MeasurementValue: 6},
+			{Name: "com.example/Lines-Covered", MeasurementValue: 0, Tags: []string{"package"}},
+		}},
+	}
+
+	assert.Equal(t, []Coverage{{Type: "Lines", Covered: 3, Missed: 1}, {Type: "Branches", Covered: 6, Missed: 2}}, FactCoverage(fact))
+}
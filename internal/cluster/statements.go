@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/gate"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+)
+
+const (
+	// gateStatementType is the statement type of the quality gate statements.
+	gateStatementType = "quality-gate"
+
+	// gateStatementName is the name of the statement summarizing the outcome of all quality gate rules.
+	gateStatementName = "Coverage-Gate"
+
+	gateTag = "gate"
+
+	// maxPackageStatements is the maximum number of failed packages written as statements for each package rule, so
+	// that the size of a Fact does not grow with the number of packages of a report.
+	maxPackageStatements = 20
+)
+
+// createStatements evaluates the configured quality gate rules against the specified report. It returns a statement
+// per evaluated rule, followed by a statement which is true only if all rules passed. Without rules no statements are
+// returned.
+//
+// A package rule results in a statement which is true only if the rule passed for all packages and whose tags count the
+// failed and evaluated packages, followed by a statement per failed package. At most maxPackageStatements failed
+// packages are written per rule, passed packages are never written.
+func (h *defaultEventHandler) createStatements(coverageReport report.Report) []jenkinsv1.Statement {
+	statements := []jenkinsv1.Statement{}
+	rules, err := gate.ParseRules(h.config.GateRules())
+	if err != nil {
+		logger.Errorf("unable to parse quality gate rules: %s", err)
+		return statements
+	}
+	if len(rules) == 0 {
+		return statements
+	}
+
+	var results []gate.Result
+	for _, rule := range rules {
+		ruleResults := gate.Evaluate([]gate.Rule{rule}, coverageReport)
+		results = append(results, ruleResults...)
+		if !rule.PerPackage {
+			for _, result := range ruleResults {
+				statements = append(statements, resultStatement(result))
+			}
+			continue
+		}
+		statements = append(statements, h.packageStatements(rule, ruleResults)...)
+	}
+	statements = append(statements, jenkinsv1.Statement{
+		Name:             gateStatementName,
+		StatementType:    gateStatementType,
+		MeasurementValue: gate.Passed(results),
+		Tags:             []string{gateTag},
+	})
+	return statements
+}
+
+// packageStatements returns the statement summarizing the results of a package rule, followed by the statements of
+// the failed packages.
+func (h *defaultEventHandler) packageStatements(rule gate.Rule, results []gate.Result) []jenkinsv1.Statement {
+	var failed []gate.Result
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	statements := []jenkinsv1.Statement{{
+		Name:             rule.String(),
+		StatementType:    gateStatementType,
+		MeasurementValue: len(failed) == 0,
+		Tags: []string{
+			gateTag,
+			fmt.Sprintf("failed=%d", len(failed)),
+			fmt.Sprintf("packages=%d", len(results)),
+		},
+	}}
+	if len(failed) > maxPackageStatements {
+		logger.Warnf("omitting %d of %d failed packages of quality gate rule '%s'", len(failed)-maxPackageStatements, len(failed), rule)
+		failed = failed[:maxPackageStatements]
+	}
+	for _, result := range failed {
+		statements = append(statements, resultStatement(result))
+	}
+	return statements
+}
+
+// resultStatement returns the statement of the result of a single rule evaluation.
+func resultStatement(result gate.Result) jenkinsv1.Statement {
+	return jenkinsv1.Statement{
+		Name:             result.String(),
+		StatementType:    gateStatementType,
+		MeasurementValue: result.Passed,
+		Tags: []string{
+			gateTag,
+			fmt.Sprintf("measured=%.2f%%", result.Coverage),
+		},
+	}
+}
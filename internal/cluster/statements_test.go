@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCreateStatements(t *testing.T) {
+	coverageReport := report.Report{
+		Counters: []report.Counter{{Type: "LINE", Missed: 10, Covered: 90}, {Type: "BRANCH", Missed: 6, Covered: 4}},
+	}
+
+	handler := defaultEventHandler{config: &testConfig{gateRules: "LINE >= 80%, BRANCH >= 60%"}}
+	statements := handler.createStatements(coverageReport)
+
+	assert.Equal(t, []jenkinsv1.Statement{
+		{Name: "LINE >= 80%", StatementType: gateStatementType, MeasurementValue: true, Tags: []string{gateTag, "measured=90.00%"}},
+		{Name: "BRANCH >= 60%", StatementType: gateStatementType, MeasurementValue: false, Tags: []string{gateTag, "measured=40.00%"}},
+		{Name: gateStatementName, StatementType: gateStatementType, MeasurementValue: false, Tags: []string{gateTag}},
+	}, statements)
+}
+
+func TestCreateStatementsWithoutRules(t *testing.T) {
+	handler := defaultEventHandler{config: &testConfig{}}
+	statements := handler.createStatements(report.Report{})
+
+	assert.Equal(t, []jenkinsv1.Statement{}, statements)
+}
+
+func TestCreateStatementsWritesOnlyFailedPackages(t *testing.T) {
+	var packages []report.Package
+	for i := 0; i < maxPackageStatements+5; i++ {
+		packages = append(packages, report.Package{
+			Name:     fmt.Sprintf("org/example/p%d", i),
+			Counters: []report.Counter{{Type: "LINE", Missed: 8, Covered: 2}},
+		})
+	}
+	packages = append(packages, report.Package{Name: "org/example/ok", Counters: []report.Counter{{Type: "LINE", Missed: 0, Covered: 1}}})
+	coverageReport := report.Report{Packages: packages}
+
+	handler := defaultEventHandler{config: &testConfig{gateRules: "package:LINE >= 50%"}}
+	statements := handler.createStatements(coverageReport)
+
+	assert.Len(t, statements, maxPackageStatements+2)
+	assert.Equal(t, jenkinsv1.Statement{
+		Name:             "package:LINE >= 50%",
+		StatementType:    gateStatementType,
+		MeasurementValue: false,
+		Tags:             []string{gateTag, fmt.Sprintf("failed=%d", maxPackageStatements+5), fmt.Sprintf("packages=%d", maxPackageStatements+6)},
+	}, statements[0])
+	assert.Equal(t, "org/example/p0: LINE >= 50%", statements[1].Name)
+	assert.Equal(t, false, statements[1].MeasurementValue)
+	assert.Equal(t, gateStatementName, statements[len(statements)-1].Name)
+}
+
+func TestCreateStatementsForPassedPackages(t *testing.T) {
+	coverageReport := report.Report{
+		Packages: []report.Package{{Name: "org/example", Counters: []report.Counter{{Type: "LINE", Missed: 1, Covered: 9}}}},
+	}
+
+	handler := defaultEventHandler{config: &testConfig{gateRules: "package:LINE >= 50%"}}
+	statements := handler.createStatements(coverageReport)
+
+	assert.Equal(t, []jenkinsv1.Statement{
+		{Name: "package:LINE >= 50%", StatementType: gateStatementType, MeasurementValue: true, Tags: []string{gateTag, "failed=0", "packages=1"}},
+		{Name: gateStatementName, StatementType: gateStatementType, MeasurementValue: true, Tags: []string{gateTag}},
+	}, statements)
+}
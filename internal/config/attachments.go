@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultAttachments are the attachments processed unless configured otherwise.
+	DefaultAttachments = "jacoco:jacoco,cobertura:cobertura,lcov:lcov,gocover:gocover,coverage:auto"
+)
+
+var (
+	validTag = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+)
+
+// Attachment configures the processing of the PipelineActivity attachments with a given name.
+type Attachment struct {
+	// Name is the name of the attachment, ie the classifier the reports were stashed with.
+	Name string
+
+	// Format is the format of the attached reports. For report.FormatUnknown the format is detected from the report
+	// URL or the report content.
+	Format report.Format
+
+	// Tag is added to the tags of the Facts created from the attachment and appended to their names, so that the
+	// attachments of a build are stored as separate Facts. The Facts of attachments without tag have no name suffix.
+	Tag string
+}
+
+// ParseAttachments parses a comma separated list of attachment specifications in the format name[:format[:tag]],
+// eg "jacoco:jacoco,jacoco-it:jacoco:integration". A missing format or the format "auto" means the format is
+// detected from the reports.
+func ParseAttachments(s string) ([]Attachment, error) {
+	var attachments []Attachment
+	names := map[string]bool{}
+	for _, spec := range util.SplitList(s) {
+		parts := strings.Split(spec, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("attachment '%s' needs to have the format name[:format[:tag]]", spec)
+		}
+
+		attachment := Attachment{Name: strings.TrimSpace(parts[0])}
+		if attachment.Name == "" {
+			return nil, fmt.Errorf("attachment '%s' has no name", spec)
+		}
+		if names[attachment.Name] {
+			return nil, fmt.Errorf("attachment '%s' is specified more than once", attachment.Name)
+		}
+		names[attachment.Name] = true
+
+		if len(parts) > 1 {
+			format, err := report.ParseFormat(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("attachment '%s': %s", spec, err)
+			}
+			attachment.Format = format
+		}
+		if len(parts) > 2 {
+			attachment.Tag = strings.TrimSpace(parts[2])
+			if !validTag.MatchString(attachment.Tag) {
+				return nil, fmt.Errorf("tag of attachment '%s' needs to consist of lower case alphanumeric characters, '-' or '.'", spec)
+			}
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// isAttachments checks if the value stored at a given key is a valid list of attachment specifications.
+func isAttachments(value interface{}, key string) error {
+	attachments, err := ParseAttachments(value.(string))
+	if err != nil {
+		return fmt.Errorf("Value for %s needs to be a list of attachments: %s", key, err)
+	}
+	if len(attachments) == 0 {
+		return fmt.Errorf("Value for %s cannot be empty.", key)
+	}
+	return nil
+}
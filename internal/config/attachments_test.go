@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAttachments(t *testing.T) {
+	attachments, err := ParseAttachments("jacoco, coverage, jacoco-it:jacoco:integration, lcov:auto:e2e")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Attachment{
+		{Name: "jacoco"},
+		{Name: "coverage"},
+		{Name: "jacoco-it", Format: report.FormatJaCoCo, Tag: "integration"},
+		{Name: "lcov", Format: report.FormatUnknown, Tag: "e2e"},
+	}, attachments)
+}
+
+func TestParseDefaultAttachments(t *testing.T) {
+	attachments, err := ParseAttachments(DefaultAttachments)
+
+	assert.NoError(t, err)
+	assert.Len(t, attachments, 5)
+	assert.Equal(t, Attachment{Name: "jacoco", Format: report.FormatJaCoCo}, attachments[0])
+}
+
+func TestParseInvalidAttachments(t *testing.T) {
+	var tests = []string{
+		"jacoco:clover",
+		"jacoco:jacoco:Unit Tests",
+		"jacoco:jacoco:unit:extra",
+		":jacoco",
+		"jacoco,jacoco:jacoco",
+	}
+
+	for _, test := range tests {
+		_, err := ParseAttachments(test)
+		assert.Error(t, err, test)
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultBadgeThresholds are the colour thresholds of coverage badges unless configured otherwise.
+	DefaultBadgeThresholds = "90:brightgreen,80:green,70:yellowgreen,60:yellow,50:orange,0:red"
+)
+
+var (
+	// badgeColors maps the named badge colours to their RGB value.
+	badgeColors = map[string]string{
+		"brightgreen": "#4c1",
+		"green":       "#97ca00",
+		"yellowgreen": "#a4a61d",
+		"yellow":      "#dfb317",
+		"orange":      "#fe7d37",
+		"red":         "#e05d44",
+		"blue":        "#007ec6",
+		"lightgrey":   "#9f9f9f",
+	}
+
+	rgbColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
+// BadgeThreshold is the colour of coverage badges whose coverage reaches a minimum percentage.
+type BadgeThreshold struct {
+	// Percent is the minimum coverage percentage.
+	Percent float64
+
+	// Color is the RGB value of the badge colour, eg #4c1.
+	Color string
+}
+
+// ParseBadgeThresholds parses a comma separated list of badge thresholds in the format percent:colour, eg
+// "80:green,60:yellow,0:red". The colour is either a named colour like brightgreen, yellowgreen, orange or lightgrey,
+// or an RGB value like #4c1. The thresholds are returned in descending order of their percentages.
+func ParseBadgeThresholds(s string) ([]BadgeThreshold, error) {
+	var thresholds []BadgeThreshold
+	for _, spec := range util.SplitList(s) {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("badge threshold '%s' needs to have the format percent:colour", spec)
+		}
+
+		percent, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("badge threshold '%s' needs a percentage between 0 and 100", spec)
+		}
+
+		color := strings.TrimSpace(parts[1])
+		if rgb, ok := badgeColors[strings.ToLower(color)]; ok {
+			color = rgb
+		} else if !rgbColor.MatchString(color) {
+			return nil, fmt.Errorf("badge threshold '%s' needs a named colour or an RGB value like #4c1", spec)
+		}
+		thresholds = append(thresholds, BadgeThreshold{Percent: percent, Color: color})
+	}
+	sort.SliceStable(thresholds, func(i, j int) bool {
+		return thresholds[i].Percent > thresholds[j].Percent
+	})
+	return thresholds, nil
+}
+
+// isBadgeThresholds checks if the value stored at a given key is a valid list of badge thresholds.
+func isBadgeThresholds(value interface{}, key string) error {
+	thresholds, err := ParseBadgeThresholds(value.(string))
+	if err != nil {
+		return fmt.Errorf("Value for %s needs to be a list of badge thresholds: %s", key, err)
+	}
+	if len(thresholds) == 0 {
+		return fmt.Errorf("Value for %s cannot be empty.", key)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseBadgeThresholds(t *testing.T) {
+	thresholds, err := ParseBadgeThresholds("0:red, 80:#0a0, 60.5:Yellow")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []BadgeThreshold{
+		{Percent: 80, Color: "#0a0"},
+		{Percent: 60.5, Color: "#dfb317"},
+		{Percent: 0, Color: "#e05d44"},
+	}, thresholds)
+}
+
+func TestParseDefaultBadgeThresholds(t *testing.T) {
+	thresholds, err := ParseBadgeThresholds(DefaultBadgeThresholds)
+
+	assert.NoError(t, err)
+	assert.Len(t, thresholds, 6)
+	assert.Equal(t, BadgeThreshold{Percent: 90, Color: "#4c1"}, thresholds[0])
+}
+
+func TestParseInvalidBadgeThresholds(t *testing.T) {
+	var tests = []string{
+		"80",
+		"80:green:bold",
+		"high:green",
+		"120:green",
+		"80:pink",
+		"80:#12345",
+	}
+
+	for _, test := range tests {
+		_, err := ParseBadgeThresholds(test)
+		assert.Error(t, err, test)
+	}
+}
@@ -1,9 +1,34 @@
 package config
 
+const (
+	// AllNamespaces selects the pipeline activities of all namespaces.
+	AllNamespaces = "*"
+
+	// FactModeMerged creates a single Fact per attachment, merging the reports of all modules.
+	FactModeMerged = "merged"
+
+	// FactModeModule creates a Fact per module if an attachment contains several reports.
+	FactModeModule = "module"
+
+	// BreakdownNone stores only the report totals in a Fact.
+	BreakdownNone = "none"
+
+	// BreakdownPackage additionally stores the measurements of each package in a Fact.
+	BreakdownPackage = "package"
+
+	// BreakdownClass additionally stores the measurements of each package and class in a Fact.
+	BreakdownClass = "class"
+)
+
 // Configuration declares the configuration properties of this app.
 type Configuration interface {
 	JXConfig
 	LogConfig
+	WorkerConfig
+	LeaderElectionConfig
+	ReportConfig
+	FactConfig
+	APIConfig
 
 	// String returns a string representation of the configuration.
 	String() string
@@ -11,8 +36,12 @@ type Configuration interface {
 
 // JXConfig defines JX specific configuration.
 type JXConfig interface {
-	// Namespace returns the JX namespace to watch.
+	// Namespace returns the JX namespace of this app.
 	Namespace() string
+
+	// WatchNamespaces returns the namespaces whose pipeline activities are processed, either a list of namespaces or
+	// AllNamespaces as single element. Defaults to the JX namespace.
+	WatchNamespaces() []string
 }
 
 // LogConfig defines the logging configuration.
@@ -20,3 +49,82 @@ type LogConfig interface {
 	// Level returns the logging level.
 	Level() string
 }
+
+// WorkerConfig defines how pipeline activities are processed.
+type WorkerConfig interface {
+	// Workers returns the number of pipeline activities processed in parallel.
+	Workers() int
+
+	// MaxRetries returns how often the processing of a pipeline activity is retried before it is given up.
+	MaxRetries() int
+
+	// ProcessStatuses returns the statuses of pipeline activities whose reports are processed. Activities with another
+	// status, eg Running, are skipped until their status changes.
+	ProcessStatuses() []string
+}
+
+// LeaderElectionConfig defines how the replicas of this app elect the replica processing pipeline activities.
+type LeaderElectionConfig interface {
+	// LeaderElection returns true if pipeline activities are only processed by the replica holding the leader lease.
+	LeaderElection() bool
+
+	// LeaseDuration returns the number of seconds other replicas wait before taking over the lease of a leader which
+	// stopped renewing it.
+	LeaseDuration() int
+
+	// LeaderIdentity returns the identity of this replica in the leader election. If empty, the hostname is used.
+	LeaderIdentity() string
+}
+
+// ReportConfig defines how coverage reports are retrieved and decoded.
+type ReportConfig interface {
+	// Attachments returns the PipelineActivity attachments which are processed.
+	Attachments() []Attachment
+
+	// StreamReports returns true if JaCoCo reports are decoded while they are read instead of being read into memory as a whole.
+	StreamReports() bool
+
+	// SkipLineDetail returns true if the line details of source files are dropped when decoding streamed reports.
+	SkipLineDetail() bool
+}
+
+// FactConfig defines how coverage Facts are created.
+type FactConfig interface {
+	// FactMode returns how the reports of an attachment are mapped to Facts, either FactModeMerged or FactModeModule.
+	FactMode() string
+
+	// OverwriteFacts returns true if existing Facts are updated when the coverage of their report changed.
+	OverwriteFacts() bool
+
+	// PercentPrecision returns the number of decimal places of coverage percentages. Percentages are stored as
+	// integers scaled by 10^PercentPrecision.
+	PercentPrecision() int
+
+	// Breakdown returns which measurements below the report totals are stored in a Fact, either BreakdownNone,
+	// BreakdownPackage or BreakdownClass.
+	Breakdown() string
+
+	// MaxFactSize returns the maximum size in bytes of a serialized Fact. Breakdown measurements exceeding this size are omitted.
+	MaxFactSize() int
+
+	// GateRules returns the comma separated minimum coverage rules of the quality gate, eg "LINE >= 80%, BRANCH >= 60%".
+	// The outcome of each rule is stored as Statement in the Fact.
+	GateRules() string
+
+	// BaseBranch returns the branch pull requests are compared against when computing the coverage delta. It applies
+	// to all repositories, the actual target branch of a pull request is not known.
+	BaseBranch() string
+
+	// MergeCoverageKinds returns true if an additional Fact combining the coverage of all kinds, eg unit and
+	// integration tests, is created for activities with several kinds of coverage.
+	MergeCoverageKinds() bool
+}
+
+// APIConfig defines how the HTTP API serves the stored coverage.
+type APIConfig interface {
+	// BadgeThresholds returns the colour thresholds of coverage badges in descending order of their percentages.
+	BadgeThresholds() []BadgeThreshold
+
+	// BadgeMaxAge returns the number of seconds clients and proxies may cache coverage badges.
+	BadgeMaxAge() int
+}
@@ -3,23 +3,58 @@ package config
 import (
 	"errors"
 	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/gate"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/logging"
 	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
 	"os"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
 var (
 	settings = map[string]Setting{}
+
+	namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
 )
 
 func init() {
 	// JX namespace
 	settings["Namespace"] = Setting{"TEAM_NAMESPACE", "jx", []func(interface{}, string) error{util.IsNotEmpty}}
+	settings["WatchNamespaces"] = Setting{"WATCH_NAMESPACES", "", []func(interface{}, string) error{isNamespaces}}
 
 	// Logging
 	settings["Level"] = Setting{"LOG_LEVEL", "info", []func(interface{}, string) error{util.IsNotEmpty}}
+
+	// Workers
+	settings["Workers"] = Setting{"WORKERS", "2", []func(interface{}, string) error{util.IsInt}}
+	settings["MaxRetries"] = Setting{"MAX_RETRIES", "5", []func(interface{}, string) error{util.IsInt}}
+	settings["ProcessStatuses"] = Setting{"PROCESS_STATUSES", "Succeeded,Failed,Aborted", []func(interface{}, string) error{util.IsNotEmpty, util.IsListOf("Succeeded", "Failed", "Error", "Aborted")}}
+
+	// Leader election
+	settings["LeaderElection"] = Setting{"LEADER_ELECTION", "true", []func(interface{}, string) error{util.IsBool}}
+	settings["LeaseDuration"] = Setting{"LEASE_DURATION", "15", []func(interface{}, string) error{util.IsPositiveInt}}
+	settings["LeaderIdentity"] = Setting{"LEADER_IDENTITY", "", []func(interface{}, string) error{}}
+
+	// Reports
+	settings["Attachments"] = Setting{"ATTACHMENTS", DefaultAttachments, []func(interface{}, string) error{isAttachments}}
+	settings["StreamReports"] = Setting{"STREAM_REPORTS", "true", []func(interface{}, string) error{util.IsBool}}
+	settings["SkipLineDetail"] = Setting{"SKIP_LINE_DETAIL", "false", []func(interface{}, string) error{util.IsBool, isNotMergingKinds}}
+
+	// Facts
+	settings["FactMode"] = Setting{"FACT_MODE", FactModeMerged, []func(interface{}, string) error{util.IsOneOf(FactModeMerged, FactModeModule)}}
+	settings["OverwriteFacts"] = Setting{"OVERWRITE_FACTS", "false", []func(interface{}, string) error{util.IsBool}}
+	settings["Breakdown"] = Setting{"BREAKDOWN", BreakdownNone, []func(interface{}, string) error{util.IsOneOf(BreakdownNone, BreakdownPackage, BreakdownClass)}}
+	settings["MaxFactSize"] = Setting{"MAX_FACT_SIZE", "1000000", []func(interface{}, string) error{util.IsInt}}
+	settings["GateRules"] = Setting{"GATE_RULES", "", []func(interface{}, string) error{isGateRules}}
+	settings["BaseBranch"] = Setting{"BASE_BRANCH", "master", []func(interface{}, string) error{util.IsNotEmpty}}
+	settings["MergeCoverageKinds"] = Setting{"MERGE_COVERAGE_KINDS", "true", []func(interface{}, string) error{util.IsBool}}
+	settings["PercentPrecision"] = Setting{"PERCENT_PRECISION", "0", []func(interface{}, string) error{util.IsOneOf("0", "1", "2", "3", "4")}}
+
+	// API
+	settings["BadgeThresholds"] = Setting{"BADGE_THRESHOLDS", DefaultBadgeThresholds, []func(interface{}, string) error{isBadgeThresholds}}
+	settings["BadgeMaxAge"] = Setting{"BADGE_MAX_AGE", "300", []func(interface{}, string) error{util.IsInt}}
 }
 
 // Setting is an element in the proxy configuration. It contains the environment
@@ -51,7 +86,7 @@ func NewConfiguration() (Configuration, error) {
 	return &config, nil
 }
 
-// Namespace returns the JX namespace to watch.
+// Namespace returns the JX namespace of this app.
 func (c *EnvConfig) Namespace() string {
 	callPtr, _, _, _ := runtime.Caller(0)
 	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
@@ -59,6 +94,19 @@ func (c *EnvConfig) Namespace() string {
 	return value
 }
 
+// WatchNamespaces returns the namespaces whose pipeline activities are processed, either a list of namespaces or
+// AllNamespaces as single element. Defaults to the JX namespace.
+func (c *EnvConfig) WatchNamespaces() []string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	namespaces := util.SplitList(value)
+	if len(namespaces) == 0 {
+		return []string{c.Namespace()}
+	}
+	return namespaces
+}
+
 // Level returns the logging level.
 func (c *EnvConfig) Level() string {
 	callPtr, _, _, _ := runtime.Caller(0)
@@ -67,6 +115,178 @@ func (c *EnvConfig) Level() string {
 	return value
 }
 
+// Workers returns the number of pipeline activities processed in parallel.
+func (c *EnvConfig) Workers() int {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+// MaxRetries returns how often the processing of a pipeline activity is retried before it is given up.
+func (c *EnvConfig) MaxRetries() int {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+// ProcessStatuses returns the statuses of pipeline activities whose reports are processed. Activities with another
+// status, eg Running, are skipped until their status changes.
+func (c *EnvConfig) ProcessStatuses() []string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	return util.SplitList(value)
+}
+
+// LeaderElection returns true if pipeline activities are only processed by the replica holding the leader lease.
+func (c *EnvConfig) LeaderElection() bool {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// LeaseDuration returns the number of seconds other replicas wait before taking over the lease of a leader which
+// stopped renewing it.
+func (c *EnvConfig) LeaseDuration() int {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+// LeaderIdentity returns the identity of this replica in the leader election. If empty, the hostname is used.
+func (c *EnvConfig) LeaderIdentity() string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	return value
+}
+
+// Attachments returns the PipelineActivity attachments which are processed.
+func (c *EnvConfig) Attachments() []Attachment {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	attachments, _ := ParseAttachments(value)
+	return attachments
+}
+
+// StreamReports returns true if JaCoCo reports are decoded while they are read instead of being read into memory as a whole.
+func (c *EnvConfig) StreamReports() bool {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// SkipLineDetail returns true if the line details of source files are dropped when decoding streamed reports.
+func (c *EnvConfig) SkipLineDetail() bool {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// FactMode returns how the reports of an attachment are mapped to Facts, either FactModeMerged or FactModeModule.
+func (c *EnvConfig) FactMode() string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	return value
+}
+
+// OverwriteFacts returns true if existing Facts are updated when the coverage of their report changed.
+func (c *EnvConfig) OverwriteFacts() bool {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// PercentPrecision returns the number of decimal places of coverage percentages. Percentages are stored as
+// integers scaled by 10^PercentPrecision.
+func (c *EnvConfig) PercentPrecision() int {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+// Breakdown returns which measurements below the report totals are stored in a Fact, either BreakdownNone,
+// BreakdownPackage or BreakdownClass.
+func (c *EnvConfig) Breakdown() string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	return value
+}
+
+// MaxFactSize returns the maximum size in bytes of a serialized Fact. Breakdown measurements exceeding this size are omitted.
+func (c *EnvConfig) MaxFactSize() int {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+// GateRules returns the comma separated minimum coverage rules of the quality gate, eg "LINE >= 80%, BRANCH >= 60%".
+// The outcome of each rule is stored as Statement in the Fact.
+func (c *EnvConfig) GateRules() string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	return value
+}
+
+// BaseBranch returns the branch pull requests are compared against when computing the coverage delta. It applies to
+// all repositories, the actual target branch of a pull request is not known.
+func (c *EnvConfig) BaseBranch() string {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	return value
+}
+
+// MergeCoverageKinds returns true if an additional Fact combining the coverage of all kinds, eg unit and
+// integration tests, is created for activities with several kinds of coverage.
+func (c *EnvConfig) MergeCoverageKinds() bool {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// BadgeThresholds returns the colour thresholds of coverage badges in descending order of their percentages.
+func (c *EnvConfig) BadgeThresholds() []BadgeThreshold {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	thresholds, _ := ParseBadgeThresholds(value)
+	return thresholds
+}
+
+// BadgeMaxAge returns the number of seconds clients and proxies may cache coverage badges.
+func (c *EnvConfig) BadgeMaxAge() int {
+	callPtr, _, _, _ := runtime.Caller(0)
+	value := getConfigValueFromEnv(util.NameOfFunction(callPtr))
+
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
 // String returns a string representation of the configuration.
 func (c *EnvConfig) String() string {
 	config := map[string]interface{}{}
@@ -96,6 +316,40 @@ func verifyEnv() util.MultiError {
 	return errors
 }
 
+// isGateRules checks if the value stored at a given key is a valid list of quality gate rules.
+func isGateRules(value interface{}, key string) error {
+	_, err := gate.ParseRules(value.(string))
+	if err != nil {
+		return fmt.Errorf("Value for %s needs to be a list of quality gate rules: %s", key, err)
+	}
+	return nil
+}
+
+// isNotMergingKinds checks that line details are not skipped while the coverage kinds are merged, since the merged
+// coverage is computed from the lines of the source files.
+func isNotMergingKinds(value interface{}, key string) error {
+	skip, _ := strconv.ParseBool(value.(string))
+	merge, _ := strconv.ParseBool(getConfigValueFromEnv("MergeCoverageKinds"))
+	if skip && merge {
+		return fmt.Errorf("Value for %s cannot be true while %s is true.", key, settings["MergeCoverageKinds"].key)
+	}
+	return nil
+}
+
+// isNamespaces checks if the value stored at a given key is a list of namespace names or AllNamespaces.
+func isNamespaces(value interface{}, key string) error {
+	namespaces := util.SplitList(value.(string))
+	for _, namespace := range namespaces {
+		if namespace == AllNamespaces && len(namespaces) == 1 {
+			continue
+		}
+		if !namespacePattern.MatchString(namespace) {
+			return fmt.Errorf("Value for %s needs to be a comma separated list of namespaces or '%s': invalid namespace '%s'", key, AllNamespaces, namespace)
+		}
+	}
+	return nil
+}
+
 func getConfigValueFromEnv(funcName string) string {
 	setting := settings[funcName]
 
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestWatchNamespaces(t *testing.T) {
+	defer os.Unsetenv("TEAM_NAMESPACE")
+	defer os.Unsetenv("WATCH_NAMESPACES")
+	os.Setenv("TEAM_NAMESPACE", "jx")
+	config := &EnvConfig{}
+
+	os.Setenv("WATCH_NAMESPACES", "")
+	assert.Equal(t, []string{"jx"}, config.WatchNamespaces())
+
+	os.Setenv("WATCH_NAMESPACES", "team-a, team-b")
+	assert.Equal(t, []string{"team-a", "team-b"}, config.WatchNamespaces())
+
+	os.Setenv("WATCH_NAMESPACES", "*")
+	assert.Equal(t, []string{AllNamespaces}, config.WatchNamespaces())
+}
+
+func TestIsNotMergingKinds(t *testing.T) {
+	defer os.Unsetenv("MERGE_COVERAGE_KINDS")
+
+	os.Setenv("MERGE_COVERAGE_KINDS", "true")
+	assert.NoError(t, isNotMergingKinds("false", "FOO"))
+	assert.Error(t, isNotMergingKinds("true", "FOO"))
+
+	os.Setenv("MERGE_COVERAGE_KINDS", "false")
+	assert.NoError(t, isNotMergingKinds("true", "FOO"))
+}
+
+func TestIsNamespaces(t *testing.T) {
+	assert.NoError(t, isNamespaces("", "FOO"))
+	assert.NoError(t, isNamespaces("jx", "FOO"))
+	assert.NoError(t, isNamespaces("jx,team-a", "FOO"))
+	assert.NoError(t, isNamespaces("*", "FOO"))
+	assert.Error(t, isNamespaces("jx,*", "FOO"))
+	assert.Error(t, isNamespaces("Team_A", "FOO"))
+}
@@ -0,0 +1,151 @@
+// Package gate implements a coverage quality gate, a set of minimum coverage rules evaluated against a report.
+package gate
+
+import (
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	"strconv"
+	"strings"
+)
+
+const (
+	// packageScope is the prefix of rules which are evaluated for each package instead of the report totals.
+	packageScope = "package:"
+
+	ruleOperator = ">="
+)
+
+var (
+	counterTypes = []string{
+		report.CounterTypeInstruction,
+		report.CounterTypeBranch,
+		report.CounterTypeLine,
+		report.CounterTypeComplexity,
+		report.CounterTypeMethod,
+		report.CounterTypeClass,
+	}
+)
+
+// Rule requires the coverage of a counter type to be at least the threshold percentage.
+type Rule struct {
+	// CounterType is the JaCoCo counter type the rule applies to, eg LINE.
+	CounterType string
+
+	// Threshold is the minimum coverage in percent.
+	Threshold float64
+
+	// PerPackage is true if the rule is evaluated for each package instead of the report totals.
+	PerPackage bool
+}
+
+// String returns the rule in the format it is parsed from, eg LINE >= 80%.
+func (r Rule) String() string {
+	scope := ""
+	if r.PerPackage {
+		scope = packageScope
+	}
+	return fmt.Sprintf("%s%s %s %s%%", scope, r.CounterType, ruleOperator, strconv.FormatFloat(r.Threshold, 'f', -1, 64))
+}
+
+// Result is the outcome of evaluating a rule.
+type Result struct {
+	Rule Rule
+
+	// Package is the name of the package the rule was evaluated for or empty for the report totals.
+	Package string
+
+	// Coverage is the measured coverage in percent. Counters without any items or missing counters count as fully covered.
+	Coverage float64
+
+	// Passed is true if the coverage meets the threshold of the rule.
+	Passed bool
+}
+
+// String returns a description of the evaluated rule, eg LINE >= 80% or org/example: LINE >= 80%.
+func (r Result) String() string {
+	rule := Rule{CounterType: r.Rule.CounterType, Threshold: r.Rule.Threshold}.String()
+	if r.Package == "" {
+		return rule
+	}
+	return fmt.Sprintf("%s: %s", r.Package, rule)
+}
+
+// ParseRules parses a comma separated list of rules, eg "LINE >= 80%, BRANCH >= 60%, package:LINE >= 50%".
+// Rules prefixed with "package:" are evaluated for each package. An empty string results in no rules.
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, text := range strings.Split(s, ",") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		rule, err := parseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(text string) (Rule, error) {
+	rule := Rule{}
+	if strings.HasPrefix(text, packageScope) {
+		rule.PerPackage = true
+		text = strings.TrimSpace(strings.TrimPrefix(text, packageScope))
+	}
+
+	parts := strings.SplitN(text, ruleOperator, 2)
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("rule '%s' needs to have the format <counter type> %s <percentage>%%", text, ruleOperator)
+	}
+
+	rule.CounterType = strings.ToUpper(strings.TrimSpace(parts[0]))
+	if !util.Contains(counterTypes, rule.CounterType) {
+		return Rule{}, fmt.Errorf("unknown counter type '%s' in rule '%s', needs to be one of [%s]", parts[0], text, strings.Join(counterTypes, ", "))
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(parts[1]), "%"), 64)
+	if err != nil || threshold < 0 || threshold > 100 {
+		return Rule{}, fmt.Errorf("threshold of rule '%s' needs to be a percentage between 0 and 100", text)
+	}
+	rule.Threshold = threshold
+	return rule, nil
+}
+
+// Evaluate evaluates the specified rules against the report. Rules for the report totals result in a single result,
+// package rules in a result per package of the report.
+func Evaluate(rules []Rule, coverageReport report.Report) []Result {
+	var results []Result
+	for _, rule := range rules {
+		if !rule.PerPackage {
+			results = append(results, evaluate(rule, "", coverageReport.Counters))
+			continue
+		}
+		for _, pkg := range coverageReport.AllPackages() {
+			results = append(results, evaluate(rule, pkg.Name, pkg.Counters))
+		}
+	}
+	return results
+}
+
+// Passed returns true if all the specified results passed.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func evaluate(rule Rule, pkg string, counters []report.Counter) Result {
+	coverage := 100.0
+	for _, c := range counters {
+		if c.Type == rule.CounterType && c.Covered+c.Missed > 0 {
+			coverage = float64(c.Covered) * 100 / float64(c.Covered+c.Missed)
+		}
+	}
+	return Result{Rule: rule, Package: pkg, Coverage: coverage, Passed: coverage >= rule.Threshold}
+}
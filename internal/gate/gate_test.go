@@ -0,0 +1,74 @@
+package gate
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/report"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("LINE >= 80%, branch>=60.5, package:LINE >= 50%")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{CounterType: report.CounterTypeLine, Threshold: 80},
+		{CounterType: report.CounterTypeBranch, Threshold: 60.5},
+		{CounterType: report.CounterTypeLine, Threshold: 50, PerPackage: true},
+	}, rules)
+	assert.Equal(t, "BRANCH >= 60.5%", rules[1].String())
+	assert.Equal(t, "package:LINE >= 50%", rules[2].String())
+}
+
+func TestParseEmptyRules(t *testing.T) {
+	rules, err := ParseRules(" ")
+
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestParseInvalidRules(t *testing.T) {
+	var tests = []string{
+		"LINE > 80%",
+		"STATEMENT >= 80%",
+		"LINE >= 120%",
+		"LINE >= eighty",
+		"LINE >= 80%, BRANCH",
+	}
+
+	for _, test := range tests {
+		_, err := ParseRules(test)
+		assert.Error(t, err, test)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	coverageReport := report.Report{
+		Counters: []report.Counter{{Type: "LINE", Missed: 20, Covered: 80}, {Type: "BRANCH", Missed: 5, Covered: 5}},
+		Packages: []report.Package{
+			{Name: "org/example/a", Counters: []report.Counter{{Type: "LINE", Missed: 20, Covered: 20}}},
+			{Name: "org/example/b", Counters: []report.Counter{{Type: "LINE", Missed: 0, Covered: 60}}},
+		},
+	}
+	rules, _ := ParseRules("LINE >= 80%, BRANCH >= 60%, package:LINE >= 50%")
+
+	results := Evaluate(rules, coverageReport)
+
+	assert.Equal(t, []Result{
+		{Rule: rules[0], Coverage: 80, Passed: true},
+		{Rule: rules[1], Coverage: 50, Passed: false},
+		{Rule: rules[2], Package: "org/example/a", Coverage: 50, Passed: true},
+		{Rule: rules[2], Package: "org/example/b", Coverage: 100, Passed: true},
+	}, results)
+	assert.Equal(t, "org/example/a: LINE >= 50%", results[2].String())
+	assert.False(t, Passed(results))
+	assert.True(t, Passed(results[2:]))
+}
+
+func TestEvaluateWithoutItems(t *testing.T) {
+	rules, _ := ParseRules("BRANCH >= 60%")
+
+	results := Evaluate(rules, report.Report{Counters: []report.Counter{{Type: "BRANCH"}}})
+
+	assert.Equal(t, 100.0, results[0].Coverage)
+	assert.True(t, results[0].Passed)
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// namespace is the prefix of the names of all metrics of this app.
+	namespace = "jacoco"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+
+	// OperationCreate is the operation of a Fact write creating a new Fact.
+	OperationCreate = "create"
+
+	// OperationUpdate is the operation of a Fact write updating an existing Fact.
+	OperationUpdate = "update"
+)
+
+// CoverageSeries identifies the coverage of a repository branch for which a gauge per counter type is exported.
+type CoverageSeries struct {
+	Namespace  string
+	Owner      string
+	Repository string
+	Branch     string
+	Kind       string
+	Module     string
+}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	reportsFetched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reports_fetched_total",
+		Help:      "Number of coverage reports fetched, by result.",
+	}, []string{"result"})
+
+	reportParseFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "report_parse_failures_total",
+		Help:      "Number of fetched coverage reports which could not be parsed, by format.",
+	}, []string{"format"})
+
+	factWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fact_writes_total",
+		Help:      "Number of Fact writes, by operation and result.",
+	}, []string{"operation", "result"})
+
+	downloadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "report_download_duration_seconds",
+		Help:      "Time taken to download a coverage report.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	})
+
+	parseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "report_parse_duration_seconds",
+		Help:      "Time taken to parse a coverage report, by format.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"format"})
+
+	coverageRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "coverage_ratio",
+		Help:      "Covered ratio of the latest processed build of a repository branch, by counter type.",
+	}, []string{"namespace", "owner", "repository", "branch", "kind", "module", "counter"})
+
+	// coverageBuilds maps the series of the coverage gauges to the build number they were last set from
+	coverageBuilds = map[CoverageSeries]int{}
+	// coverageCounters maps the series of the coverage gauges to the counter types a gauge was set for
+	coverageCounters = map[CoverageSeries]map[string]bool{}
+	coverageLock     sync.Mutex
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+		reportsFetched,
+		reportParseFailures,
+		factWrites,
+		downloadDuration,
+		parseDuration,
+		coverageRatio,
+	)
+}
+
+// Handler returns the HTTP handler exposing the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveDownload records the download of a coverage report started at the specified time. A download which failed
+// with the specified error is only counted.
+func ObserveDownload(start time.Time, err error) {
+	if err != nil {
+		reportsFetched.WithLabelValues(resultFailure).Inc()
+		return
+	}
+	reportsFetched.WithLabelValues(resultSuccess).Inc()
+	downloadDuration.Observe(time.Since(start).Seconds())
+}
+
+// ObserveParse records the parsing of a coverage report in the specified format started at the specified time.
+// Parsing which failed with the specified error is counted as parse failure.
+func ObserveParse(format string, start time.Time, err error) {
+	if format == "" {
+		format = "unknown"
+	}
+	if err != nil {
+		reportParseFailures.WithLabelValues(format).Inc()
+		return
+	}
+	parseDuration.WithLabelValues(format).Observe(time.Since(start).Seconds())
+}
+
+// ObserveFactWrite records a Fact write with the specified operation, either OperationCreate or OperationUpdate,
+// which failed with the specified error if not nil.
+func ObserveFactWrite(operation string, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+	}
+	factWrites.WithLabelValues(operation, result).Inc()
+}
+
+// SetCoverage sets the coverage gauge of the specified series and counter type to the ratio of covered to total
+// items, unless the gauges of the series were already set from a later build. The ratio of a counter without items is 1.
+func SetCoverage(series CoverageSeries, build int, counterType string, covered int, total int) {
+	coverageLock.Lock()
+	defer coverageLock.Unlock()
+
+	if latest, ok := coverageBuilds[series]; ok && latest > build {
+		return
+	}
+	coverageBuilds[series] = build
+	counter := strings.ToLower(counterType)
+	if coverageCounters[series] == nil {
+		coverageCounters[series] = map[string]bool{}
+	}
+	coverageCounters[series][counter] = true
+
+	ratio := 1.0
+	if total > 0 {
+		ratio = float64(covered) / float64(total)
+	}
+	coverageRatio.WithLabelValues(series.Namespace, series.Owner, series.Repository, series.Branch, series.Kind,
+		series.Module, counter).Set(ratio)
+}
+
+// DeleteCoverage deletes the coverage gauges of the specified series, if they were last set from the specified build.
+// The gauges of a series set from a later build are kept.
+func DeleteCoverage(series CoverageSeries, build int) {
+	coverageLock.Lock()
+	defer coverageLock.Unlock()
+
+	if latest, ok := coverageBuilds[series]; !ok || latest != build {
+		return
+	}
+	for counter := range coverageCounters[series] {
+		coverageRatio.DeleteLabelValues(series.Namespace, series.Owner, series.Repository, series.Branch, series.Kind,
+			series.Module, counter)
+	}
+	delete(coverageBuilds, series)
+	delete(coverageCounters, series)
+}
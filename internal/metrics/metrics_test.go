@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestObserveDownload(t *testing.T) {
+	successes := testutil.ToFloat64(reportsFetched.WithLabelValues(resultSuccess))
+	failures := testutil.ToFloat64(reportsFetched.WithLabelValues(resultFailure))
+
+	ObserveDownload(time.Now(), nil)
+	ObserveDownload(time.Now(), errors.New("not found"))
+
+	assert.Equal(t, successes+1, testutil.ToFloat64(reportsFetched.WithLabelValues(resultSuccess)))
+	assert.Equal(t, failures+1, testutil.ToFloat64(reportsFetched.WithLabelValues(resultFailure)))
+}
+
+func TestObserveParse(t *testing.T) {
+	failures := testutil.ToFloat64(reportParseFailures.WithLabelValues("unknown"))
+
+	ObserveParse("", time.Now(), errors.New("unable to detect report format"))
+
+	assert.Equal(t, failures+1, testutil.ToFloat64(reportParseFailures.WithLabelValues("unknown")))
+}
+
+func TestObserveFactWrite(t *testing.T) {
+	creates := testutil.ToFloat64(factWrites.WithLabelValues(OperationCreate, resultSuccess))
+	failedUpdates := testutil.ToFloat64(factWrites.WithLabelValues(OperationUpdate, resultFailure))
+
+	ObserveFactWrite(OperationCreate, nil)
+	ObserveFactWrite(OperationUpdate, errors.New("conflict"))
+
+	assert.Equal(t, creates+1, testutil.ToFloat64(factWrites.WithLabelValues(OperationCreate, resultSuccess)))
+	assert.Equal(t, failedUpdates+1, testutil.ToFloat64(factWrites.WithLabelValues(OperationUpdate, resultFailure)))
+}
+
+func TestSetCoverage(t *testing.T) {
+	series := CoverageSeries{Namespace: "jx", Owner: "foo", Repository: "bar", Branch: "master", Kind: "unit"}
+	gauge := coverageRatio.WithLabelValues("jx", "foo", "bar", "master", "unit", "", "lines")
+
+	SetCoverage(series, 2, "Lines", 80, 100)
+	assert.Equal(t, 0.8, testutil.ToFloat64(gauge))
+
+	// coverage of an older build processed later is ignored
+	SetCoverage(series, 1, "Lines", 10, 100)
+	assert.Equal(t, 0.8, testutil.ToFloat64(gauge))
+
+	SetCoverage(series, 3, "Lines", 0, 0)
+	assert.Equal(t, 1.0, testutil.ToFloat64(gauge))
+}
+
+func TestDeleteCoverage(t *testing.T) {
+	series := CoverageSeries{Namespace: "jx", Owner: "foo", Repository: "bar", Branch: "develop", Kind: "unit"}
+	SetCoverage(series, 2, "Lines", 80, 100)
+	SetCoverage(series, 2, "Branches", 1, 2)
+
+	// the gauges of an older build are already replaced
+	DeleteCoverage(series, 1)
+	assert.Equal(t, 0.8, testutil.ToFloat64(coverageRatio.WithLabelValues("jx", "foo", "bar", "develop", "unit", "", "lines")))
+
+	DeleteCoverage(series, 2)
+	assert.NotContains(t, coverageBuilds, series)
+	assert.NotContains(t, coverageCounters, series)
+	assert.False(t, coverageRatio.DeleteLabelValues("jx", "foo", "bar", "develop", "unit", "", "lines"))
+	assert.False(t, coverageRatio.DeleteLabelValues("jx", "foo", "bar", "develop", "unit", "", "branches"))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveFactWrite(OperationCreate, nil)
+	recorder := httptest.NewRecorder()
+
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `jacoco_fact_writes_total{operation="create",result="success"}`)
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+)
+
+// coberturaReport is the top level struct for the Cobertura report.
+type coberturaReport struct {
+	XMLName  xml.Name           `xml:"coverage"`
+	Packages []coberturaPackage `xml:"packages>package"`
+}
+
+// coberturaPackage depicts a package, module or directory in a Cobertura report.
+type coberturaPackage struct {
+	Name    string           `xml:"name,attr"`
+	Classes []coberturaClass `xml:"classes>class"`
+}
+
+// coberturaClass depicts a class or source file in a Cobertura report.
+type coberturaClass struct {
+	Name     string            `xml:"name,attr"`
+	Filename string            `xml:"filename,attr"`
+	Methods  []coberturaMethod `xml:"methods>method"`
+	Lines    []coberturaLine   `xml:"lines>line"`
+}
+
+// coberturaMethod depicts a method or function in a Cobertura report.
+type coberturaMethod struct {
+	Name      string          `xml:"name,attr"`
+	Signature string          `xml:"signature,attr"`
+	Lines     []coberturaLine `xml:"lines>line"`
+}
+
+// coberturaLine depicts a line in a Cobertura report.
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr"`
+}
+
+// toLine converts a Cobertura line into a Line of the internal model.
+// Each line is treated as a single instruction, branches are taken from the condition coverage, eg '50% (1/2)'.
+func (l coberturaLine) toLine() Line {
+	line := Line{Nr: l.Number}
+	if l.Hits > 0 {
+		line.Ci = 1
+	} else {
+		line.Mi = 1
+	}
+
+	if l.Branch {
+		var percent, covered, total int
+		if _, err := fmt.Sscanf(l.ConditionCoverage, "%d%% (%d/%d)", &percent, &covered, &total); err == nil {
+			line.Cb = covered
+			line.Mb = total - covered
+		}
+	}
+	return line
+}
+
+func parseCobertura(data []byte) (Report, error) {
+	cobertura := coberturaReport{}
+	err := xml.Unmarshal(data, &cobertura)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{}
+	reportCounters := counterSet{}
+	for _, p := range cobertura.Packages {
+		pkg := convertCoberturaPackage(p)
+		reportCounters.addAll(pkg.Counters)
+		report.Packages = append(report.Packages, pkg)
+	}
+	report.Counters = reportCounters.counters()
+	return report, nil
+}
+
+func convertCoberturaPackage(p coberturaPackage) Package {
+	pkg := Package{Name: p.Name}
+
+	// several classes can share a source file, hence lines are collected per file
+	fileLines := map[string]map[int]Line{}
+	classCounters := counterSet{}
+	for _, c := range p.Classes {
+		class := convertCoberturaClass(c)
+		for _, counter := range class.Counters {
+			if counter.Type == CounterTypeMethod || counter.Type == CounterTypeClass {
+				classCounters.add(counter.Type, counter.Missed, counter.Covered)
+			}
+		}
+		pkg.Classes = append(pkg.Classes, class)
+
+		lines, ok := fileLines[c.Filename]
+		if !ok {
+			lines = map[int]Line{}
+			fileLines[c.Filename] = lines
+		}
+		for _, l := range c.Lines {
+			lines[l.Number] = mergeLine(lines[l.Number], l.toLine())
+		}
+	}
+
+	packageCounters := counterSet{}
+	for _, fileName := range sortedKeys(fileLines) {
+		sourceFile := SourceFile{Name: fileName, Lines: sortedLines(fileLines[fileName])}
+		sourceFile.Counters = lineCounters(sourceFile.Lines)
+		packageCounters.addAll(sourceFile.Counters)
+		pkg.SourceFiles = append(pkg.SourceFiles, sourceFile)
+	}
+	packageCounters.addAll(classCounters.counters())
+	pkg.Counters = packageCounters.counters()
+	return pkg
+}
+
+func convertCoberturaClass(c coberturaClass) Class {
+	class := Class{Name: c.Name, Sourcefilename: c.Filename}
+
+	methodCounters := counterSet{}
+	for _, m := range c.Methods {
+		method := Method{Name: m.Name, Desc: m.Signature}
+		lines := make([]Line, 0, len(m.Lines))
+		for _, l := range m.Lines {
+			lines = append(lines, l.toLine())
+		}
+		if len(lines) > 0 {
+			method.Line = lines[0].Nr
+		}
+		counters := counterSet{}
+		counters.addAll(lineCounters(lines))
+		if isCovered(lines) {
+			counters.add(CounterTypeMethod, 0, 1)
+		} else {
+			counters.add(CounterTypeMethod, 1, 0)
+		}
+		method.Counters = counters.counters()
+		methodCounters.add(CounterTypeMethod, counters[CounterTypeMethod].Missed, counters[CounterTypeMethod].Covered)
+		class.Methods = append(class.Methods, method)
+	}
+
+	lines := make([]Line, 0, len(c.Lines))
+	for _, l := range c.Lines {
+		lines = append(lines, l.toLine())
+	}
+	counters := counterSet{}
+	counters.addAll(lineCounters(lines))
+	counters.addAll(methodCounters.counters())
+	if isCovered(lines) {
+		counters.add(CounterTypeClass, 0, 1)
+	} else {
+		counters.add(CounterTypeClass, 1, 0)
+	}
+	class.Counters = counters.counters()
+	return class
+}
+
+func sortedKeys(m map[string]map[int]Line) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
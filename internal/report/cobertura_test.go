@@ -0,0 +1,37 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseCobertura(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/cobertura.xml")
+	assert.NoError(t, err)
+
+	report, err := Parse(data, FormatCobertura)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCobertura, report.Format)
+	assert.Equal(t, []Counter{
+		{Type: CounterTypeBranch, Missed: 1, Covered: 1},
+		{Type: CounterTypeLine, Missed: 2, Covered: 3},
+		{Type: CounterTypeMethod, Missed: 1, Covered: 1},
+		{Type: CounterTypeClass, Missed: 0, Covered: 1},
+	}, report.Counters)
+
+	assert.Len(t, report.Packages, 1)
+	pkg := report.Packages[0]
+	assert.Equal(t, "demo", pkg.Name)
+	assert.Len(t, pkg.Classes, 1)
+	assert.Len(t, pkg.Classes[0].Methods, 2)
+	assert.Equal(t, 3, pkg.Classes[0].Methods[0].Line)
+	assert.Len(t, pkg.SourceFiles, 1)
+	assert.Equal(t, "demo/app.py", pkg.SourceFiles[0].Name)
+	assert.Equal(t, Line{Nr: 4, Ci: 1, Mb: 1, Cb: 1}, pkg.SourceFiles[0].Lines[2])
+}
+
+func TestParseCoberturaInvalidXML(t *testing.T) {
+	_, err := Parse([]byte("<coverage><packages>"), FormatCobertura)
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package report
+
+// counterTypes lists the counter types in the order JaCoCo reports them.
+var counterTypes = []string{
+	CounterTypeInstruction,
+	CounterTypeBranch,
+	CounterTypeLine,
+	CounterTypeComplexity,
+	CounterTypeMethod,
+	CounterTypeClass,
+}
+
+// counterSet accumulates missed and covered counts per counter type.
+type counterSet map[string]*Counter
+
+// add adds the specified missed and covered counts to the counter of type t.
+func (s counterSet) add(t string, missed int, covered int) {
+	c, ok := s[t]
+	if !ok {
+		c = &Counter{Type: t}
+		s[t] = c
+	}
+	c.Missed += missed
+	c.Covered += covered
+}
+
+// addAll adds all the specified counters to this set.
+func (s counterSet) addAll(counters []Counter) {
+	for _, c := range counters {
+		s.add(c.Type, c.Missed, c.Covered)
+	}
+}
+
+// counters returns the counters of this set in JaCoCo order.
+func (s counterSet) counters() []Counter {
+	counters := make([]Counter, 0, len(s))
+	for _, t := range counterTypes {
+		if c, ok := s[t]; ok {
+			counters = append(counters, *c)
+		}
+	}
+	return counters
+}
+
+// lineCounters computes the LINE and BRANCH counters for the specified lines.
+// A line counts as covered if at least one of its instructions was executed.
+func lineCounters(lines []Line) []Counter {
+	set := counterSet{}
+	for _, line := range lines {
+		switch {
+		case line.Ci > 0:
+			set.add(CounterTypeLine, 0, 1)
+		case line.Mi > 0:
+			set.add(CounterTypeLine, 1, 0)
+		}
+		if line.Mb > 0 || line.Cb > 0 {
+			set.add(CounterTypeBranch, line.Mb, line.Cb)
+		}
+	}
+	return set.counters()
+}
@@ -0,0 +1,149 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Format identifies the format of a coverage report.
+type Format string
+
+const (
+	// FormatUnknown is used when the report format is not known upfront and needs to be detected.
+	FormatUnknown Format = ""
+	// FormatJaCoCo is the JaCoCo XML report format.
+	FormatJaCoCo Format = "jacoco"
+	// FormatCobertura is the Cobertura XML report format.
+	FormatCobertura Format = "cobertura"
+	// FormatLCOV is the LCOV tracefile format.
+	FormatLCOV Format = "lcov"
+	// FormatGoCover is the coverage profile format of 'go test -coverprofile'.
+	FormatGoCover Format = "gocover"
+)
+
+// formatInfo contains the parser and the meta data for a report format.
+type formatInfo struct {
+	parse    func(data []byte) (Report, error)
+	mimeType string
+	fileName string
+}
+
+var (
+	formats = map[Format]formatInfo{
+		FormatJaCoCo:    {parse: parseJaCoCo, mimeType: "application/xml", fileName: "jacoco.xml"},
+		FormatCobertura: {parse: parseCobertura, mimeType: "application/xml", fileName: "cobertura.xml"},
+		FormatLCOV:      {parse: parseLCOV, mimeType: "text/plain", fileName: "lcov.info"},
+		FormatGoCover:   {parse: parseGoCover, mimeType: "text/plain", fileName: "coverage.out"},
+	}
+
+	// xmlRootElements maps the root element of an XML report to its format.
+	xmlRootElements = map[string]Format{
+		"report":   FormatJaCoCo,
+		"coverage": FormatCobertura,
+	}
+
+	// textPrefixes maps the start of a plain text report to its format.
+	textPrefixes = map[string]Format{
+		"TN:":   FormatLCOV,
+		"SF:":   FormatLCOV,
+		"mode:": FormatGoCover,
+	}
+)
+
+// MimeType returns the MIME type of reports in this format.
+func (f Format) MimeType() string {
+	return formats[f].mimeType
+}
+
+// FileName returns the conventional file name of reports in this format.
+func (f Format) FileName() string {
+	return formats[f].fileName
+}
+
+// ParseFormat returns the report format with the specified name. The names "auto" and "" result in FormatUnknown,
+// ie the format is detected from the report.
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "auto" {
+		return FormatUnknown, nil
+	}
+	if _, ok := formats[Format(name)]; !ok {
+		return FormatUnknown, fmt.Errorf("unknown report format '%s'", name)
+	}
+	return Format(name), nil
+}
+
+// FormatForName returns the report format matching the specified name, for example an attachment name or a URL.
+// FormatUnknown is returned if the name does not identify a format.
+func FormatForName(name string) Format {
+	if u, err := url.Parse(name); err == nil && u.Path != "" {
+		name = u.Path
+	}
+	name = strings.ToLower(path.Base(name))
+
+	for format, info := range formats {
+		if name == string(format) || name == info.fileName {
+			return format
+		}
+	}
+	return FormatUnknown
+}
+
+// DetectFormat detects the format of the specified raw report.
+// The format is derived from the root element of XML reports and from the first record of plain text reports.
+// FormatUnknown is returned if the format cannot be detected.
+func DetectFormat(data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		for prefix, format := range textPrefixes {
+			if bytes.HasPrefix(trimmed, []byte(prefix)) {
+				return format
+			}
+		}
+		return FormatUnknown
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return FormatUnknown
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return xmlRootElements[start.Name.Local]
+		}
+	}
+}
+
+// Parse parses the specified raw report using the parser for the specified format.
+// If format is FormatUnknown, the format is detected from the report content.
+func Parse(data []byte, format Format) (Report, error) {
+	if format == FormatUnknown {
+		format = DetectFormat(data)
+		if format == FormatUnknown {
+			return Report{}, fmt.Errorf("unable to detect report format")
+		}
+	}
+
+	info, ok := formats[format]
+	if !ok {
+		return Report{}, fmt.Errorf("unsupported report format '%s'", format)
+	}
+
+	report, err := info.parse(data)
+	if err != nil {
+		return report, err
+	}
+	report.Format = format
+	return report, nil
+}
+
+func parseJaCoCo(data []byte) (Report, error) {
+	report := Report{}
+	err := xml.Unmarshal(data, &report)
+	return report, err
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormatForName(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		expectedFormat Format
+	}{
+		{"jacoco", FormatJaCoCo},
+		{"cobertura", FormatCobertura},
+		{"http://foo.bar/target/site/jacoco/jacoco.xml?version=1", FormatJaCoCo},
+		{"gs://bucket/build/cobertura.xml", FormatCobertura},
+		{"lcov", FormatLCOV},
+		{"http://foo.bar/coverage/lcov.info?version=1", FormatLCOV},
+		{"gocover", FormatGoCover},
+		{"http://foo.bar/coverage.out", FormatGoCover},
+		{"http://foo.bar/coverage.xml", FormatUnknown},
+		{"coverage", FormatUnknown},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedFormat, FormatForName(testCase.name), testCase.name)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	var testCases = []struct {
+		fixture        string
+		expectedFormat Format
+	}{
+		{"testdata/jacoco.xml", FormatJaCoCo},
+		{"testdata/cobertura.xml", FormatCobertura},
+		{"testdata/lcov.info", FormatLCOV},
+		{"testdata/coverage.out", FormatGoCover},
+	}
+
+	for _, testCase := range testCases {
+		data, err := ioutil.ReadFile(testCase.fixture)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedFormat, DetectFormat(data), testCase.fixture)
+	}
+
+	assert.Equal(t, FormatUnknown, DetectFormat([]byte("<html></html>")))
+	assert.Equal(t, FormatUnknown, DetectFormat([]byte("foo")))
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	_, err := Parse([]byte("<html></html>"), FormatUnknown)
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		expectedFormat Format
+		expectError    bool
+	}{
+		{"jacoco", FormatJaCoCo, false},
+		{"Cobertura", FormatCobertura, false},
+		{"auto", FormatUnknown, false},
+		{"", FormatUnknown, false},
+		{"clover", FormatUnknown, true},
+	}
+
+	for _, testCase := range testCases {
+		format, err := ParseFormat(testCase.name)
+		assert.Equal(t, testCase.expectedFormat, format, testCase.name)
+		assert.Equal(t, testCase.expectError, err != nil, testCase.name)
+	}
+}
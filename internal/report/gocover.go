@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	"path"
+	"sort"
+	"strings"
+)
+
+// goCoverModes lists the valid modes of a Go coverage profile.
+var goCoverModes = []string{"set", "count", "atomic"}
+
+// goCoverBlock is a single block of a Go coverage profile.
+type goCoverBlock struct {
+	startLine  int
+	startCol   int
+	endLine    int
+	endCol     int
+	statements int
+	count      int
+}
+
+func parseGoCover(data []byte) (Report, error) {
+	// blocks per file, keyed by the position of the block, so that blocks of concatenated profiles are merged
+	files := map[string]map[string]*goCoverBlock{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNr := 0
+	modeSeen := false
+	for scanner.Scan() {
+		lineNr++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "mode:") {
+			mode := strings.TrimSpace(strings.TrimPrefix(text, "mode:"))
+			if !util.Contains(goCoverModes, mode) {
+				return Report{}, fmt.Errorf("unsupported Go coverage mode '%s' at line %d", mode, lineNr)
+			}
+			modeSeen = true
+			continue
+		}
+		if !modeSeen {
+			return Report{}, fmt.Errorf("coverage profile needs to start with a mode line")
+		}
+
+		// <file>:<start line>.<start column>,<end line>.<end column> <number of statements> <count>
+		i := strings.LastIndex(text, ":")
+		if i < 0 {
+			return Report{}, fmt.Errorf("invalid Go coverage block at line %d: '%s'", lineNr, text)
+		}
+		fileName, position := text[:i], text[i+1:]
+		block := goCoverBlock{}
+		_, err := fmt.Sscanf(position, "%d.%d,%d.%d %d %d", &block.startLine, &block.startCol, &block.endLine, &block.endCol, &block.statements, &block.count)
+		if err != nil {
+			return Report{}, fmt.Errorf("invalid Go coverage block at line %d: '%s'", lineNr, text)
+		}
+
+		blocks, ok := files[fileName]
+		if !ok {
+			blocks = map[string]*goCoverBlock{}
+			files[fileName] = blocks
+		}
+		key := strings.Fields(position)[0]
+		if existing, ok := blocks[key]; ok {
+			existing.count += block.count
+		} else {
+			blocks[key] = &block
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Report{}, err
+	}
+
+	return goCoverReport(files), nil
+}
+
+// goCoverSourceFile converts the blocks of a single file into a SourceFile of the internal model.
+// Statements are reported as instructions, a line counts as covered if one of the blocks spanning it was executed.
+func goCoverSourceFile(fileName string, blocks map[string]*goCoverBlock) SourceFile {
+	counters := counterSet{}
+	lines := map[int]Line{}
+	for _, block := range blocks {
+		line := Line{}
+		if block.count > 0 {
+			counters.add(CounterTypeInstruction, 0, block.statements)
+			line.Ci = 1
+		} else {
+			counters.add(CounterTypeInstruction, block.statements, 0)
+			line.Mi = 1
+		}
+		for nr := block.startLine; nr <= block.endLine; nr++ {
+			line.Nr = nr
+			lines[nr] = mergeLine(lines[nr], line)
+		}
+	}
+
+	sourceFile := SourceFile{Name: path.Base(fileName), Lines: sortedLines(lines)}
+	counters.addAll(lineCounters(sourceFile.Lines))
+	sourceFile.Counters = counters.counters()
+	return sourceFile
+}
+
+// goCoverReport builds the report from the parsed blocks, grouping the files into packages by import path.
+func goCoverReport(files map[string]map[string]*goCoverBlock) Report {
+	packages := map[string]*Package{}
+	var packageNames []string
+	for fileName, blocks := range files {
+		dir := path.Dir(fileName)
+		pkg, ok := packages[dir]
+		if !ok {
+			pkg = &Package{Name: dir}
+			packages[dir] = pkg
+			packageNames = append(packageNames, dir)
+		}
+		pkg.SourceFiles = append(pkg.SourceFiles, goCoverSourceFile(fileName, blocks))
+	}
+	sort.Strings(packageNames)
+
+	report := Report{}
+	reportCounters := counterSet{}
+	for _, name := range packageNames {
+		pkg := packages[name]
+		sort.Slice(pkg.SourceFiles, func(i, j int) bool {
+			return pkg.SourceFiles[i].Name < pkg.SourceFiles[j].Name
+		})
+		packageCounters := counterSet{}
+		for _, sourceFile := range pkg.SourceFiles {
+			packageCounters.addAll(sourceFile.Counters)
+		}
+		pkg.Counters = packageCounters.counters()
+		reportCounters.addAll(pkg.Counters)
+		report.Packages = append(report.Packages, *pkg)
+	}
+	report.Counters = reportCounters.counters()
+	return report
+}
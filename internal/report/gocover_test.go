@@ -0,0 +1,43 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseGoCover(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/coverage.out")
+	assert.NoError(t, err)
+
+	report, err := Parse(data, FormatUnknown)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatGoCover, report.Format)
+	assert.Equal(t, []Counter{
+		{Type: CounterTypeInstruction, Missed: 2, Covered: 5},
+		{Type: CounterTypeLine, Missed: 3, Covered: 8},
+	}, report.Counters)
+
+	assert.Len(t, report.Packages, 2)
+	assert.Equal(t, "github.com/example/demo", report.Packages[0].Name)
+	assert.Equal(t, "main.go", report.Packages[0].SourceFiles[0].Name)
+	assert.Equal(t, []Counter{
+		{Type: CounterTypeInstruction, Missed: 0, Covered: 5},
+		{Type: CounterTypeLine, Missed: 0, Covered: 8},
+	}, report.Packages[0].Counters)
+	assert.Equal(t, "github.com/example/demo/internal/util", report.Packages[1].Name)
+}
+
+func TestParseGoCoverInvalidProfile(t *testing.T) {
+	var testCases = []string{
+		"foo.go:1.1,2.2 1 1\n",
+		"mode: foo\n",
+		"mode: set\nfoo.go 1 1\n",
+		"mode: set\nfoo.go:1.1,2.2 x 1\n",
+	}
+
+	for _, testCase := range testCases {
+		_, err := Parse([]byte(testCase), FormatGoCover)
+		assert.Error(t, err, testCase)
+	}
+}
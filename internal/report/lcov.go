@@ -0,0 +1,292 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lcovFile accumulates the records of a single source file of a LCOV tracefile.
+// The same source file can appear in several records, eg one per test name, in which case the records are merged.
+type lcovFile struct {
+	name string
+
+	lines         map[int]Line
+	branches      map[string]lcovBranch
+	functionLines map[string]int
+	functionHits  map[string]int
+
+	// summary values, only used if the tracefile does not contain the detail records
+	linesFound     int
+	linesHit       int
+	branchesFound  int
+	branchesHit    int
+	functionsFound int
+	functionsHit   int
+}
+
+// lcovBranch is a single branch of a BRDA record.
+type lcovBranch struct {
+	line  int
+	taken bool
+}
+
+func newLcovFile(name string) *lcovFile {
+	return &lcovFile{
+		name:          name,
+		lines:         map[int]Line{},
+		branches:      map[string]lcovBranch{},
+		functionLines: map[string]int{},
+		functionHits:  map[string]int{},
+	}
+}
+
+func parseLCOV(data []byte) (Report, error) {
+	files := map[string]*lcovFile{}
+	var current *lcovFile
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if text == "end_of_record" {
+			current = nil
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 {
+			return Report{}, fmt.Errorf("invalid LCOV record at line %d: '%s'", lineNr, text)
+		}
+		key, value := parts[0], parts[1]
+
+		if key == "TN" {
+			continue
+		}
+		if key == "SF" {
+			file, ok := files[value]
+			if !ok {
+				file = newLcovFile(value)
+				files[value] = file
+			}
+			current = file
+			continue
+		}
+		if current == nil {
+			return Report{}, fmt.Errorf("LCOV record outside of a source file section at line %d: '%s'", lineNr, text)
+		}
+		if err := current.addRecord(key, value); err != nil {
+			return Report{}, fmt.Errorf("invalid LCOV record at line %d: %s", lineNr, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Report{}, err
+	}
+
+	return lcovReport(files), nil
+}
+
+// addRecord adds a single record to this file.
+func (f *lcovFile) addRecord(key string, value string) error {
+	fields := strings.Split(value, ",")
+	var err error
+	switch key {
+	case "DA":
+		// DA:<line number>,<execution count>[,<checksum>]
+		var nr, count int
+		if nr, err = fieldAsInt(fields, 0); err != nil {
+			return err
+		}
+		if count, err = fieldAsInt(fields, 1); err != nil {
+			return err
+		}
+		line := Line{Nr: nr}
+		if count > 0 {
+			line.Ci = 1
+		} else {
+			line.Mi = 1
+		}
+		f.lines[nr] = mergeLine(f.lines[nr], line)
+	case "BRDA":
+		// BRDA:<line number>,<block number>,<branch number>,<taken>
+		if len(fields) != 4 {
+			return fmt.Errorf("expected 4 fields in BRDA record '%s'", value)
+		}
+		var nr int
+		if nr, err = fieldAsInt(fields, 0); err != nil {
+			return err
+		}
+		taken := false
+		if fields[3] != "-" {
+			count, err := fieldAsInt(fields, 3)
+			if err != nil {
+				return err
+			}
+			taken = count > 0
+		}
+		id := strings.Join(fields[:3], ",")
+		f.branches[id] = lcovBranch{line: nr, taken: taken || f.branches[id].taken}
+	case "FN":
+		// FN:<line number of function start>,<function name>
+		if len(fields) < 2 {
+			return fmt.Errorf("expected 2 fields in FN record '%s'", value)
+		}
+		var nr int
+		if nr, err = fieldAsInt(fields, 0); err != nil {
+			return err
+		}
+		name := strings.Join(fields[1:], ",")
+		f.functionLines[name] = nr
+		if _, ok := f.functionHits[name]; !ok {
+			f.functionHits[name] = 0
+		}
+	case "FNDA":
+		// FNDA:<execution count>,<function name>
+		if len(fields) < 2 {
+			return fmt.Errorf("expected 2 fields in FNDA record '%s'", value)
+		}
+		var count int
+		if count, err = fieldAsInt(fields, 0); err != nil {
+			return err
+		}
+		f.functionHits[strings.Join(fields[1:], ",")] += count
+	case "LF":
+		f.linesFound, err = summaryValue(f.linesFound, value)
+	case "LH":
+		f.linesHit, err = summaryValue(f.linesHit, value)
+	case "BRF":
+		f.branchesFound, err = summaryValue(f.branchesFound, value)
+	case "BRH":
+		f.branchesHit, err = summaryValue(f.branchesHit, value)
+	case "FNF":
+		f.functionsFound, err = summaryValue(f.functionsFound, value)
+	case "FNH":
+		f.functionsHit, err = summaryValue(f.functionsHit, value)
+	}
+	return err
+}
+
+// toClass converts this file into a Class and a SourceFile of the internal model.
+func (f *lcovFile) toClass() (Class, SourceFile) {
+	for _, branch := range f.branches {
+		line := f.lines[branch.line]
+		line.Nr = branch.line
+		if branch.taken {
+			line.Cb++
+		} else {
+			line.Mb++
+		}
+		f.lines[branch.line] = line
+	}
+	lines := sortedLines(f.lines)
+
+	counters := counterSet{}
+	lineCounts := lineCounters(lines)
+	counters.addAll(lineCounts)
+	if counterOfType(lineCounts, CounterTypeLine) == nil && f.linesFound > 0 {
+		counters.add(CounterTypeLine, f.linesFound-f.linesHit, f.linesHit)
+	}
+	if counterOfType(lineCounts, CounterTypeBranch) == nil && f.branchesFound > 0 {
+		counters.add(CounterTypeBranch, f.branchesFound-f.branchesHit, f.branchesHit)
+	}
+
+	class := Class{Name: f.name, Sourcefilename: path.Base(f.name)}
+	for name, nr := range f.functionLines {
+		method := Method{Name: name, Line: nr}
+		if f.functionHits[name] > 0 {
+			method.Counters = []Counter{{Type: CounterTypeMethod, Covered: 1}}
+		} else {
+			method.Counters = []Counter{{Type: CounterTypeMethod, Missed: 1}}
+		}
+		counters.addAll(method.Counters)
+		class.Methods = append(class.Methods, method)
+	}
+	sort.Slice(class.Methods, func(i, j int) bool {
+		return class.Methods[i].Line < class.Methods[j].Line
+	})
+	if len(f.functionLines) == 0 && f.functionsFound > 0 {
+		counters.add(CounterTypeMethod, f.functionsFound-f.functionsHit, f.functionsHit)
+	}
+	class.Counters = counters.counters()
+
+	sourceFile := SourceFile{Name: path.Base(f.name), Lines: lines, Counters: class.Counters}
+	return class, sourceFile
+}
+
+// lcovReport builds the report from the parsed files, grouping the files into packages by directory.
+func lcovReport(files map[string]*lcovFile) Report {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := Report{}
+	packages := map[string]*Package{}
+	var packageNames []string
+	for _, name := range names {
+		class, sourceFile := files[name].toClass()
+		dir := path.Dir(name)
+		pkg, ok := packages[dir]
+		if !ok {
+			pkg = &Package{Name: dir}
+			packages[dir] = pkg
+			packageNames = append(packageNames, dir)
+		}
+		pkg.Classes = append(pkg.Classes, class)
+		pkg.SourceFiles = append(pkg.SourceFiles, sourceFile)
+	}
+
+	reportCounters := counterSet{}
+	for _, name := range packageNames {
+		pkg := packages[name]
+		packageCounters := counterSet{}
+		for _, class := range pkg.Classes {
+			packageCounters.addAll(class.Counters)
+		}
+		pkg.Counters = packageCounters.counters()
+		reportCounters.addAll(pkg.Counters)
+		report.Packages = append(report.Packages, *pkg)
+	}
+	report.Counters = reportCounters.counters()
+	return report
+}
+
+// counterOfType returns the counter of the specified type or nil if there is none.
+func counterOfType(counters []Counter, t string) *Counter {
+	for i := range counters {
+		if counters[i].Type == t {
+			return &counters[i]
+		}
+	}
+	return nil
+}
+
+func fieldAsInt(fields []string, i int) (int, error) {
+	if i >= len(fields) {
+		return 0, fmt.Errorf("missing field %d", i+1)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %d is not a number: '%s'", i+1, fields[i])
+	}
+	return int(value), nil
+}
+
+// summaryValue parses a summary record value. If a file appears in several records, the largest value is kept.
+func summaryValue(current int, value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return current, fmt.Errorf("summary value is not a number: '%s'", value)
+	}
+	return maxInt(current, n), nil
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseLCOV(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/lcov.info")
+	assert.NoError(t, err)
+
+	report, err := Parse(data, FormatUnknown)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatLCOV, report.Format)
+	assert.Equal(t, []Counter{
+		{Type: CounterTypeBranch, Missed: 1, Covered: 1},
+		{Type: CounterTypeLine, Missed: 1, Covered: 8},
+		{Type: CounterTypeMethod, Missed: 0, Covered: 3},
+	}, report.Counters)
+
+	assert.Len(t, report.Packages, 2)
+	pkg := report.Packages[0]
+	assert.Equal(t, "/home/jenkins/demo/src", pkg.Name)
+	assert.Len(t, pkg.Classes, 1)
+	assert.Len(t, pkg.Classes[0].Methods, 2)
+	assert.Equal(t, "main", pkg.Classes[0].Methods[0].Name)
+	assert.Equal(t, "app.js", pkg.SourceFiles[0].Name)
+	assert.Equal(t, Line{Nr: 4, Ci: 1, Mb: 1, Cb: 1}, pkg.SourceFiles[0].Lines[2])
+	assert.Equal(t, Line{Nr: 8, Ci: 1}, pkg.SourceFiles[0].Lines[4])
+
+	assert.Equal(t, "/home/jenkins/demo/src/util", report.Packages[1].Name)
+	assert.Equal(t, []Counter{
+		{Type: CounterTypeLine, Missed: 0, Covered: 4},
+		{Type: CounterTypeMethod, Missed: 0, Covered: 1},
+	}, report.Packages[1].Counters)
+}
+
+func TestParseLCOVInvalidRecord(t *testing.T) {
+	var testCases = []string{
+		"DA:1,1\n",
+		"SF:foo.js\nDA:x,1\n",
+		"SF:foo.js\nBRDA:1,0,0\n",
+		"SF:foo.js\nLF:many\n",
+		"SF:foo.js\ngarbage\n",
+	}
+
+	for _, testCase := range testCases {
+		_, err := Parse([]byte(testCase), FormatLCOV)
+		assert.Error(t, err, testCase)
+	}
+}
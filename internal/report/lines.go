@@ -0,0 +1,42 @@
+package report
+
+import "sort"
+
+// mergeLine merges the coverage data of two lines with the same line number.
+// Covered instructions and branches are the maximum of both lines, the missed ones the remainder of the larger total.
+func mergeLine(a Line, b Line) Line {
+	merged := Line{Nr: b.Nr}
+	merged.Ci = maxInt(a.Ci, b.Ci)
+	merged.Mi = maxInt(a.Mi+a.Ci, b.Mi+b.Ci) - merged.Ci
+	merged.Cb = maxInt(a.Cb, b.Cb)
+	merged.Mb = maxInt(a.Mb+a.Cb, b.Mb+b.Cb) - merged.Cb
+	return merged
+}
+
+// isCovered returns true if at least one of the specified lines is covered.
+func isCovered(lines []Line) bool {
+	for _, line := range lines {
+		if line.Ci > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedLines(lines map[int]Line) []Line {
+	sorted := make([]Line, 0, len(lines))
+	for _, line := range lines {
+		sorted = append(sorted, line)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Nr < sorted[j].Nr
+	})
+	return sorted
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
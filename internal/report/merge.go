@@ -0,0 +1,261 @@
+package report
+
+import (
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/util"
+	"strings"
+)
+
+// Merge merges the specified reports of the same code, eg the reports of unit and integration tests of a module, into
+// a single report. The reports of distinct modules are combined with Combine instead.
+//
+// Packages, classes, methods and source files are matched by name. The counters of distinct elements add up, whereas
+// elements contained in several reports are assumed to describe the same code. For those the covered counts are the
+// maximum of the individual reports and the lines of source files are combined, so that a line covered in any report
+// counts as covered. The line, branch and, for JaCoCo reports, instruction counters of such source files are computed
+// from the combined lines. All counters above the class level are recomputed from the merged elements. Session infos
+// are combined as well.
+func Merge(reports ...Report) Report {
+	if len(reports) == 1 {
+		return reports[0]
+	}
+
+	// only the lines of JaCoCo reports contain instruction counts
+	instructionLines := true
+	for _, r := range reports {
+		if r.Format != FormatJaCoCo {
+			instructionLines = false
+		}
+	}
+
+	merged := Report{}
+	var names []string
+	sessionIDs := map[string]bool{}
+	packages := map[string]*Package{}
+	var packageNames []string
+	countersWithoutPackages := counterSet{}
+	for i, r := range reports {
+		if i == 0 {
+			merged.XMLName = r.XMLName
+			merged.Format = r.Format
+		}
+		if r.Name != "" && !util.Contains(names, r.Name) {
+			names = append(names, r.Name)
+		}
+		for _, sessionInfo := range r.SessionInfo {
+			if !sessionIDs[sessionInfo.ID] {
+				sessionIDs[sessionInfo.ID] = true
+				merged.SessionInfo = append(merged.SessionInfo, sessionInfo)
+			}
+		}
+		for _, p := range r.Packages {
+			existing, ok := packages[p.Name]
+			if !ok {
+				pkg := p
+				packages[p.Name] = &pkg
+				packageNames = append(packageNames, p.Name)
+				continue
+			}
+			*existing = mergePackage(*existing, p, instructionLines)
+		}
+		merged.Groups = append(merged.Groups, r.Groups...)
+
+		// reports decoded without packages only have their top level counters
+		if len(r.Packages) == 0 && len(r.Groups) == 0 {
+			countersWithoutPackages.addAll(r.Counters)
+		}
+	}
+	merged.Name = strings.Join(names, ",")
+
+	counters := counterSet{}
+	for _, name := range packageNames {
+		pkg := packages[name]
+		merged.Packages = append(merged.Packages, *pkg)
+		counters.addAll(pkg.Counters)
+	}
+	for _, group := range merged.Groups {
+		counters.addAll(group.Counters)
+	}
+	counters.addAll(countersWithoutPackages.counters())
+	merged.Counters = counters.counters()
+	return merged
+}
+
+// Combine combines the specified reports of distinct modules into a single report. The counters of all reports add
+// up, even if modules contain packages or source files of the same name, so the totals do not depend on whether the
+// packages of the reports were decoded. Packages of the same name are combined into one package containing the
+// classes and source files of all modules. Session infos are combined as well.
+func Combine(reports ...Report) Report {
+	if len(reports) == 1 {
+		return reports[0]
+	}
+
+	combined := Report{}
+	var names []string
+	sessionIDs := map[string]bool{}
+	packages := map[string]*Package{}
+	var packageNames []string
+	counters := counterSet{}
+	for i, r := range reports {
+		if i == 0 {
+			combined.XMLName = r.XMLName
+			combined.Format = r.Format
+		}
+		if r.Name != "" && !util.Contains(names, r.Name) {
+			names = append(names, r.Name)
+		}
+		for _, sessionInfo := range r.SessionInfo {
+			if !sessionIDs[sessionInfo.ID] {
+				sessionIDs[sessionInfo.ID] = true
+				combined.SessionInfo = append(combined.SessionInfo, sessionInfo)
+			}
+		}
+		for _, p := range r.Packages {
+			existing, ok := packages[p.Name]
+			if !ok {
+				pkg := p
+				packages[p.Name] = &pkg
+				packageNames = append(packageNames, p.Name)
+				continue
+			}
+			*existing = combinePackage(*existing, p)
+		}
+		combined.Groups = append(combined.Groups, r.Groups...)
+		counters.addAll(r.Counters)
+	}
+	combined.Name = strings.Join(names, ",")
+	for _, name := range packageNames {
+		combined.Packages = append(combined.Packages, *packages[name])
+	}
+	combined.Counters = counters.counters()
+	return combined
+}
+
+// combinePackage combines two packages of the same name in distinct modules, adding up their counters.
+func combinePackage(a Package, b Package) Package {
+	counters := counterSet{}
+	counters.addAll(a.Counters)
+	counters.addAll(b.Counters)
+	return Package{
+		Name:        a.Name,
+		Classes:     append(append([]Class{}, a.Classes...), b.Classes...),
+		SourceFiles: append(append([]SourceFile{}, a.SourceFiles...), b.SourceFiles...),
+		Counters:    counters.counters(),
+	}
+}
+
+func mergePackage(a Package, b Package, instructionLines bool) Package {
+	merged := Package{Name: a.Name}
+
+	classIndex := map[string]int{}
+	for _, class := range append(append([]Class{}, a.Classes...), b.Classes...) {
+		i, ok := classIndex[class.Name]
+		if !ok {
+			classIndex[class.Name] = len(merged.Classes)
+			merged.Classes = append(merged.Classes, class)
+			continue
+		}
+		merged.Classes[i] = mergeClass(merged.Classes[i], class)
+	}
+
+	sourceFileIndex := map[string]int{}
+	for _, sourceFile := range append(append([]SourceFile{}, a.SourceFiles...), b.SourceFiles...) {
+		i, ok := sourceFileIndex[sourceFile.Name]
+		if !ok {
+			sourceFileIndex[sourceFile.Name] = len(merged.SourceFiles)
+			merged.SourceFiles = append(merged.SourceFiles, sourceFile)
+			continue
+		}
+		merged.SourceFiles[i] = mergeSourceFile(merged.SourceFiles[i], sourceFile, instructionLines)
+	}
+
+	// counter types which are not tracked per source file, eg methods and classes of Cobertura reports, are taken from the classes
+	counters := counterSet{}
+	for _, sourceFile := range merged.SourceFiles {
+		counters.addAll(sourceFile.Counters)
+	}
+	classCounters := counterSet{}
+	for _, class := range merged.Classes {
+		classCounters.addAll(class.Counters)
+	}
+	for _, c := range classCounters.counters() {
+		if _, ok := counters[c.Type]; !ok {
+			counters.add(c.Type, c.Missed, c.Covered)
+		}
+	}
+	merged.Counters = counters.counters()
+	return merged
+}
+
+func mergeClass(a Class, b Class) Class {
+	merged := Class{Name: a.Name, Sourcefilename: a.Sourcefilename}
+
+	methodIndex := map[string]int{}
+	for _, method := range append(append([]Method{}, a.Methods...), b.Methods...) {
+		key := method.Name + method.Desc
+		i, ok := methodIndex[key]
+		if !ok {
+			methodIndex[key] = len(merged.Methods)
+			merged.Methods = append(merged.Methods, method)
+			continue
+		}
+		existing := merged.Methods[i]
+		merged.Methods[i] = Method{
+			Name:     existing.Name,
+			Desc:     existing.Desc,
+			Line:     existing.Line,
+			Counters: maxCounters(existing.Counters, method.Counters),
+		}
+	}
+	merged.Counters = maxCounters(a.Counters, b.Counters)
+	return merged
+}
+
+// mergeSourceFile merges two versions of the same source file. If instructionLines is true, the Mi and Ci values of the
+// lines are instruction counts.
+func mergeSourceFile(a SourceFile, b SourceFile, instructionLines bool) SourceFile {
+	merged := SourceFile{Name: a.Name}
+
+	lines := map[int]Line{}
+	for _, line := range append(append([]Line{}, a.Lines...), b.Lines...) {
+		lines[line.Nr] = mergeLine(lines[line.Nr], line)
+	}
+	if len(lines) > 0 {
+		merged.Lines = sortedLines(lines)
+	}
+
+	counters := counterSet{}
+	counters.addAll(maxCounters(a.Counters, b.Counters))
+	if len(a.Lines) > 0 && len(b.Lines) > 0 {
+		for _, c := range lineCounters(merged.Lines) {
+			counter := c
+			counters[c.Type] = &counter
+		}
+		if _, ok := counters[CounterTypeInstruction]; ok && instructionLines {
+			instructions := &Counter{Type: CounterTypeInstruction}
+			for _, line := range merged.Lines {
+				instructions.Missed += line.Mi
+				instructions.Covered += line.Ci
+			}
+			counters[CounterTypeInstruction] = instructions
+		}
+	}
+	merged.Counters = counters.counters()
+	return merged
+}
+
+// maxCounters combines the counters of two versions of the same element.
+// The covered count is the maximum of both counters, the missed count the remainder of the larger total.
+func maxCounters(a []Counter, b []Counter) []Counter {
+	covered := map[string]int{}
+	total := map[string]int{}
+	for _, c := range append(append([]Counter{}, a...), b...) {
+		covered[c.Type] = maxInt(covered[c.Type], c.Covered)
+		total[c.Type] = maxInt(total[c.Type], c.Covered+c.Missed)
+	}
+
+	merged := counterSet{}
+	for t := range covered {
+		merged.add(t, total[t]-covered[t], covered[t])
+	}
+	return merged.counters()
+}
@@ -0,0 +1,131 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMergeSingleReport(t *testing.T) {
+	report := Report{Name: "demo", Counters: []Counter{{Type: CounterTypeLine, Missed: 1, Covered: 2}}}
+	assert.Equal(t, report, Merge(report))
+}
+
+func TestMergeDistinctModules(t *testing.T) {
+	moduleA := Report{
+		Name:        "module-a",
+		SessionInfo: []SessionInfo{{ID: "a"}},
+		Packages: []Package{
+			{
+				Name:        "com/example/a",
+				SourceFiles: []SourceFile{{Name: "A.java", Counters: []Counter{{Type: CounterTypeLine, Missed: 1, Covered: 3}}}},
+				Counters:    []Counter{{Type: CounterTypeLine, Missed: 1, Covered: 3}},
+			},
+		},
+		Counters: []Counter{{Type: CounterTypeLine, Missed: 1, Covered: 3}},
+	}
+	moduleB := Report{
+		Name:        "module-b",
+		SessionInfo: []SessionInfo{{ID: "a"}, {ID: "b"}},
+		Packages: []Package{
+			{
+				Name:        "com/example/b",
+				SourceFiles: []SourceFile{{Name: "B.java", Counters: []Counter{{Type: CounterTypeLine, Missed: 4, Covered: 2}}}},
+				Counters:    []Counter{{Type: CounterTypeLine, Missed: 4, Covered: 2}},
+			},
+		},
+		Counters: []Counter{{Type: CounterTypeLine, Missed: 4, Covered: 2}},
+	}
+
+	merged := Merge(moduleA, moduleB)
+	assert.Equal(t, "module-a,module-b", merged.Name)
+	assert.Equal(t, []SessionInfo{{ID: "a"}, {ID: "b"}}, merged.SessionInfo)
+	assert.Len(t, merged.Packages, 2)
+	assert.Equal(t, []Counter{{Type: CounterTypeLine, Missed: 5, Covered: 5}}, merged.Counters)
+}
+
+func TestMergeOverlappingReports(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/jacoco.xml")
+	assert.NoError(t, err)
+	unitTests, err := Parse(data, FormatJaCoCo)
+	assert.NoError(t, err)
+
+	// integration tests covering the main method
+	integrationTests, err := Parse(data, FormatJaCoCo)
+	assert.NoError(t, err)
+	integrationTests.SessionInfo = []SessionInfo{{ID: "it"}}
+	sourceFile := &integrationTests.Packages[0].SourceFiles[0]
+	sourceFile.Lines = []Line{{Nr: 7, Mi: 3}, {Nr: 10, Ci: 3}, {Nr: 11, Ci: 4}, {Nr: 12, Ci: 1}}
+	sourceFile.Counters = []Counter{{Type: CounterTypeInstruction, Missed: 3, Covered: 8}, {Type: CounterTypeLine, Missed: 1, Covered: 3}}
+	integrationTests.Packages[0].Classes[0].Counters = []Counter{{Type: CounterTypeInstruction, Missed: 3, Covered: 8}}
+
+	merged := Merge(unitTests, integrationTests)
+	assert.Equal(t, "demo", merged.Name)
+	assert.Len(t, merged.SessionInfo, 2)
+	assert.Len(t, merged.Packages, 1)
+	assert.Len(t, merged.Packages[0].Classes, 1)
+	assert.Len(t, merged.Packages[0].Classes[0].Methods, 2)
+	assert.Equal(t, Counter{Type: CounterTypeInstruction, Missed: 3, Covered: 8}, *counterOfType(merged.Packages[0].Classes[0].Counters, CounterTypeInstruction))
+
+	mergedSourceFile := merged.Packages[0].SourceFiles[0]
+	assert.Equal(t, []Line{{Nr: 7, Ci: 3}, {Nr: 10, Ci: 3}, {Nr: 11, Ci: 4}, {Nr: 12, Ci: 1}}, mergedSourceFile.Lines)
+	assert.Equal(t, Counter{Type: CounterTypeLine, Missed: 0, Covered: 4}, *counterOfType(mergedSourceFile.Counters, CounterTypeLine))
+	assert.Equal(t, Counter{Type: CounterTypeLine, Missed: 0, Covered: 4}, *counterOfType(merged.Counters, CounterTypeLine))
+	// the instructions of the source file are computed from the combined lines
+	assert.Equal(t, Counter{Type: CounterTypeInstruction, Missed: 0, Covered: 11}, *counterOfType(mergedSourceFile.Counters, CounterTypeInstruction))
+	assert.Equal(t, Counter{Type: CounterTypeInstruction, Missed: 0, Covered: 11}, *counterOfType(merged.Counters, CounterTypeInstruction))
+}
+
+func TestMergeNonJaCoCoReportsKeepsInstructionCounters(t *testing.T) {
+	a := Report{Format: FormatGoCover, Packages: []Package{{Name: "p", SourceFiles: []SourceFile{{
+		Name:     "f.go",
+		Lines:    []Line{{Nr: 1, Ci: 1}, {Nr: 2, Mi: 1}},
+		Counters: []Counter{{Type: CounterTypeInstruction, Missed: 4, Covered: 2}},
+	}}}}}
+	b := Report{Format: FormatGoCover, Packages: []Package{{Name: "p", SourceFiles: []SourceFile{{
+		Name:     "f.go",
+		Lines:    []Line{{Nr: 1, Mi: 1}, {Nr: 2, Ci: 1}},
+		Counters: []Counter{{Type: CounterTypeInstruction, Missed: 3, Covered: 3}},
+	}}}}}
+
+	merged := Merge(a, b)
+	assert.Equal(t, Counter{Type: CounterTypeLine, Missed: 0, Covered: 2}, *counterOfType(merged.Counters, CounterTypeLine))
+	assert.Equal(t, Counter{Type: CounterTypeInstruction, Missed: 3, Covered: 3}, *counterOfType(merged.Counters, CounterTypeInstruction))
+}
+
+func TestMergeReportsWithoutPackages(t *testing.T) {
+	a := Report{Counters: []Counter{{Type: CounterTypeLine, Missed: 1, Covered: 3}}}
+	b := Report{Counters: []Counter{{Type: CounterTypeLine, Missed: 2, Covered: 2}, {Type: CounterTypeClass, Missed: 0, Covered: 1}}}
+
+	merged := Merge(a, b)
+	assert.Equal(t, []Counter{{Type: CounterTypeLine, Missed: 3, Covered: 5}, {Type: CounterTypeClass, Missed: 0, Covered: 1}}, merged.Counters)
+}
+
+func TestCombineModulesWithSameNames(t *testing.T) {
+	module := func(name string, missed int, covered int) Report {
+		counters := []Counter{{Type: CounterTypeLine, Missed: missed, Covered: covered}}
+		return Report{
+			Name: name,
+			Packages: []Package{{
+				Name:        "com/acme",
+				Classes:     []Class{{Name: "com/acme/Application", Counters: counters}},
+				SourceFiles: []SourceFile{{Name: "Application.java", Counters: counters}},
+				Counters:    counters,
+			}},
+			Counters: counters,
+		}
+	}
+
+	combined := Combine(module("module-a", 1, 3), module("module-b", 4, 2))
+	assert.Equal(t, "module-a,module-b", combined.Name)
+	assert.Equal(t, []Counter{{Type: CounterTypeLine, Missed: 5, Covered: 5}}, combined.Counters)
+	assert.Len(t, combined.Packages, 1)
+	assert.Equal(t, []Counter{{Type: CounterTypeLine, Missed: 5, Covered: 5}}, combined.Packages[0].Counters)
+	assert.Len(t, combined.Packages[0].SourceFiles, 2)
+
+	// the totals are the same without packages
+	withoutPackages := func(r Report) Report {
+		return Report{Name: r.Name, Counters: r.Counters}
+	}
+	assert.Equal(t, combined.Counters, Combine(withoutPackages(module("module-a", 1, 3)), withoutPackages(module("module-b", 4, 2))).Counters)
+}
@@ -2,9 +2,25 @@ package report
 
 import "encoding/xml"
 
+const (
+	// CounterTypeInstruction is the counter type for instructions.
+	CounterTypeInstruction = "INSTRUCTION"
+	// CounterTypeBranch is the counter type for branches.
+	CounterTypeBranch = "BRANCH"
+	// CounterTypeLine is the counter type for lines.
+	CounterTypeLine = "LINE"
+	// CounterTypeComplexity is the counter type for the cyclomatic complexity.
+	CounterTypeComplexity = "COMPLEXITY"
+	// CounterTypeMethod is the counter type for methods.
+	CounterTypeMethod = "METHOD"
+	// CounterTypeClass is the counter type for classes.
+	CounterTypeClass = "CLASS"
+)
+
 // Report is the top level struct for the jacoco report
 type Report struct {
 	XMLName     xml.Name      `xml:"report"`
+	Format      Format        `xml:"-"`
 	Name        string        `xml:"name,attr"`
 	SessionInfo []SessionInfo `xml:"sessioninfo"`
 	Packages    []Package     `xml:"package"`
@@ -12,6 +28,19 @@ type Report struct {
 	Counters    []Counter     `xml:"counter"`
 }
 
+// AllPackages returns the packages of the report followed by the packages of its groups and their sub groups.
+func (r Report) AllPackages() []Package {
+	return packagesOf(r.Packages, r.Groups)
+}
+
+func packagesOf(packages []Package, groups []Group) []Package {
+	all := append([]Package{}, packages...)
+	for _, group := range groups {
+		all = append(all, packagesOf(group.Packages, group.Groups)...)
+	}
+	return all
+}
+
 // Counter keeps track over misses and coverage of various the source constructs.
 type Counter struct {
 	Type    string `xml:"type,attr"`
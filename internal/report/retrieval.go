@@ -1,13 +1,30 @@
 package report
 
 import (
-	"encoding/xml"
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/jenkins-x-apps/jx-app-jacoco/internal/metrics"
 	"github.com/jenkins-x/jx/pkg/cloud/buckets"
 	"github.com/jenkins-x/jx/pkg/jx/cmd"
 	"github.com/jenkins-x/jx/pkg/jx/cmd/clients"
+	"gocloud.dev/blob"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
 )
 
+const (
+	// sniffSize is the number of bytes looked at for detecting the format of a streamed report.
+	sniffSize = 4096
+
+	// versionParameter is the query parameter appended to report URLs to avoid retrieving cached reports.
+	versionParameter = "version"
+)
+
 var (
 	timeout           = time.Second * 30
 	r       retriever = &defaultRetriever{}
@@ -15,6 +32,7 @@ var (
 
 type retriever interface {
 	getRawReport(namespace string, url string) ([]byte, error)
+	openReport(namespace string, url string) (io.ReadCloser, error)
 }
 
 type defaultRetriever struct {
@@ -36,16 +54,152 @@ func (r *defaultRetriever) getRawReport(namepace string, url string) ([]byte, er
 	return data, nil
 }
 
+// openReport opens a stream to the specified report. Reports served via HTTP(S) are streamed from the response body,
+// reports in cloud storage buckets, eg gs:// or s3:// URLs, are streamed via a reader of the bucket.
+func (r *defaultRetriever) openReport(namespace string, url string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return openBucketReport(url)
+	}
+
+	common := cmd.NewCommonOptions(namespace, clients.NewFactory())
+	authSvc, err := common.CreateGitAuthConfigService()
+	if err != nil {
+		return nil, err
+	}
+
+	requestURL, headerFn, err := cmd.CreateBucketHTTPFn(authSvc)(url)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if headerFn != nil {
+		headerFn(request)
+	}
+
+	client := http.Client{Timeout: timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		response.Body.Close()
+		return nil, fmt.Errorf("status %s when performing GET on %s", response.Status, url)
+	}
+	return response.Body, nil
+}
+
+// openBucketReport opens a reader of the object referenced by the specified bucket URL, eg
+// gs://bucket/path/jacoco.xml. The reader is cancelled after the retrieval timeout. Query parameters other than the
+// version parameter appended to avoid caching are passed on to the bucket.
+func openBucketReport(bucketURL string) (io.ReadCloser, error) {
+	u, err := neturl.Parse(bucketURL)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	query := u.Query()
+	query.Del(versionParameter)
+	u.Path = ""
+	u.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	bucket, err := blob.OpenBucket(ctx, u.String())
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("unable to open bucket %s: %s", u.String(), err)
+	}
+	reader, err := bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("unable to read %s from bucket %s: %s", key, u.String(), err)
+	}
+	return &cancelingReadCloser{ReadCloser: reader, cancel: cancel}, nil
+}
+
+// cancelingReadCloser cancels the context of a reader when it is closed.
+type cancelingReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
 // RetrieveReport retrieves a JaCoCo report from the specified URL which can be on GitHub or a cloud storage bucket.
+// The report format is detected from the URL or the report content, so other supported formats are retrieved as well.
 func RetrieveReport(namespace string, url string) (Report, error) {
+	return RetrieveFormattedReport(namespace, url, FormatForName(url))
+}
+
+// RetrieveFormattedReport retrieves a report of the specified format from the specified URL.
+// If format is FormatUnknown, the format is detected from the report content.
+func RetrieveFormattedReport(namespace string, url string, format Format) (Report, error) {
+	start := time.Now()
 	rawReport, err := r.getRawReport(namespace, url)
+	metrics.ObserveDownload(start, err)
+	if err != nil {
+		return Report{}, err
+	}
+
+	start = time.Now()
+	report, err := Parse(rawReport, format)
+	observeParse(format, report, start, err)
+	return report, err
+}
+
+// StreamReport retrieves a report of the specified format from the specified URL like RetrieveFormattedReport.
+// JaCoCo reports are however decoded while they are read, keeping only the details requested via options.
+// Reports in other formats are parsed as a whole.
+func StreamReport(namespace string, url string, format Format, options DecodeOptions) (Report, error) {
+	start := time.Now()
+	reader, err := r.openReport(namespace, url)
+	metrics.ObserveDownload(start, err)
 	if err != nil {
 		return Report{}, err
 	}
-	report := Report{}
-	err = xml.Unmarshal(rawReport, &report)
+	defer reader.Close()
+
+	// the parse time of streamed reports includes reading the response body
+	start = time.Now()
+	report, err := decodeStream(reader, format, options)
+	observeParse(format, report, start, err)
+	return report, err
+}
+
+// decodeStream decodes a report of the specified format from the specified reader.
+func decodeStream(reader io.Reader, format Format, options DecodeOptions) (Report, error) {
+	buffered := bufio.NewReaderSize(reader, sniffSize)
+	if format == FormatUnknown {
+		// Peek returns an error if the report is shorter than sniffSize, the peeked bytes are still valid
+		head, _ := buffered.Peek(sniffSize)
+		format = DetectFormat(head)
+	}
+
+	if format != FormatJaCoCo {
+		data, err := ioutil.ReadAll(buffered)
+		if err != nil {
+			return Report{}, err
+		}
+		return Parse(data, format)
+	}
+
+	report, err := DecodeJaCoCo(buffered, options)
 	if err != nil {
-		return report, err
+		return Report{}, err
 	}
+	report.Format = FormatJaCoCo
 	return report, nil
 }
+
+// observeParse records the parsing of a report which was requested in the specified format.
+func observeParse(format Format, report Report, start time.Time, err error) {
+	if report.Format != FormatUnknown {
+		format = report.Format
+	}
+	metrics.ObserveParse(string(format), start, err)
+}
@@ -3,7 +3,9 @@ package report
 import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -14,6 +16,10 @@ func (r *errorThrowingRetriever) getRawReport(namespace string, url string) ([]b
 	return nil, errors.New("Unable to retrieve report")
 }
 
+func (r *errorThrowingRetriever) openReport(namespace string, url string) (io.ReadCloser, error) {
+	return nil, errors.New("Unable to retrieve report")
+}
+
 type fixtureRetriever struct {
 }
 
@@ -22,6 +28,10 @@ func (r *fixtureRetriever) getRawReport(namespace string, url string) ([]byte, e
 	return data, err
 }
 
+func (r *fixtureRetriever) openReport(namespace string, url string) (io.ReadCloser, error) {
+	return os.Open("testdata/jacoco.xml")
+}
+
 func TestRetrieveReportWithError(t *testing.T) {
 	origRetriever := r
 	defer func() {
@@ -46,3 +56,29 @@ func TestRetrieveReportSuccess(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "demo", report.Name)
 }
+
+func TestStreamReportWithError(t *testing.T) {
+	origRetriever := r
+	defer func() {
+		r = origRetriever
+	}()
+	r = &errorThrowingRetriever{}
+
+	report, err := StreamReport("jx", "http://foo.bar/jacoco.xml", FormatJaCoCo, DecodeOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, Report{}, report)
+}
+
+func TestStreamReportSuccess(t *testing.T) {
+	origRetriever := r
+	defer func() {
+		r = origRetriever
+	}()
+	r = &fixtureRetriever{}
+
+	report, err := StreamReport("jx", "http://foo.bar/report", FormatUnknown, DecodeOptions{SkipLines: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "demo", report.Name)
+	assert.Equal(t, FormatJaCoCo, report.Format)
+	assert.Len(t, report.Counters, 5)
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// DecodeOptions controls which details of a JaCoCo report are kept when decoding the report as a stream.
+type DecodeOptions struct {
+	// SkipLines drops the <line> elements of source files. The counters of the source files are kept.
+	SkipLines bool
+
+	// SkipPackages drops all package and group elements. Only the session infos and report counters are kept, which
+	// is all a Fact without breakdown measurements and package quality gate rules needs.
+	SkipPackages bool
+}
+
+// sourceFileSummary is used for decoding a source file without its lines.
+type sourceFileSummary struct {
+	Name     string    `xml:"name,attr"`
+	Counters []Counter `xml:"counter"`
+}
+
+// DecodeJaCoCo decodes a JaCoCo XML report from the specified reader.
+// In contrast to xml.Unmarshal the report is decoded element by element, so that only the details requested via
+// the options are kept in memory. Unless SkipPackages is set, all packages, classes and methods are retained, so the
+// memory usage still grows with the number of classes. With SkipPackages only the report counters are retained and
+// the memory usage does not depend on the report size.
+func DecodeJaCoCo(reader io.Reader, options DecodeOptions) (Report, error) {
+	decoder := xml.NewDecoder(reader)
+
+	var root *xml.StartElement
+	for root == nil {
+		token, err := decoder.Token()
+		if err != nil {
+			return Report{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = &start
+		}
+	}
+	if root.Name.Local != "report" {
+		return Report{}, fmt.Errorf("expected element type <report> but have <%s>", root.Name.Local)
+	}
+
+	report := Report{XMLName: root.Name, Name: attr(*root, "name")}
+	err := decodeChildren(decoder, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "sessioninfo":
+			sessionInfo := SessionInfo{}
+			if err := decoder.DecodeElement(&sessionInfo, &start); err != nil {
+				return err
+			}
+			report.SessionInfo = append(report.SessionInfo, sessionInfo)
+		case "counter":
+			counter := Counter{}
+			if err := decoder.DecodeElement(&counter, &start); err != nil {
+				return err
+			}
+			report.Counters = append(report.Counters, counter)
+		case "package":
+			if options.SkipPackages {
+				return decoder.Skip()
+			}
+			pkg, err := decodePackage(decoder, start, options)
+			if err != nil {
+				return err
+			}
+			report.Packages = append(report.Packages, pkg)
+		case "group":
+			if options.SkipPackages {
+				return decoder.Skip()
+			}
+			group, err := decodeGroup(decoder, start, options)
+			if err != nil {
+				return err
+			}
+			report.Groups = append(report.Groups, group)
+		default:
+			return decoder.Skip()
+		}
+		return nil
+	})
+	if err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+func decodeGroup(decoder *xml.Decoder, start xml.StartElement, options DecodeOptions) (Group, error) {
+	group := Group{Name: attr(start, "name")}
+	err := decodeChildren(decoder, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "counter":
+			counter := Counter{}
+			if err := decoder.DecodeElement(&counter, &start); err != nil {
+				return err
+			}
+			group.Counters = append(group.Counters, counter)
+		case "package":
+			pkg, err := decodePackage(decoder, start, options)
+			if err != nil {
+				return err
+			}
+			group.Packages = append(group.Packages, pkg)
+		case "group":
+			subGroup, err := decodeGroup(decoder, start, options)
+			if err != nil {
+				return err
+			}
+			group.Groups = append(group.Groups, subGroup)
+		default:
+			return decoder.Skip()
+		}
+		return nil
+	})
+	return group, err
+}
+
+func decodePackage(decoder *xml.Decoder, start xml.StartElement, options DecodeOptions) (Package, error) {
+	pkg := Package{Name: attr(start, "name")}
+	err := decodeChildren(decoder, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "counter":
+			counter := Counter{}
+			if err := decoder.DecodeElement(&counter, &start); err != nil {
+				return err
+			}
+			pkg.Counters = append(pkg.Counters, counter)
+		case "class":
+			class := Class{}
+			if err := decoder.DecodeElement(&class, &start); err != nil {
+				return err
+			}
+			pkg.Classes = append(pkg.Classes, class)
+		case "sourcefile":
+			if options.SkipLines {
+				summary := sourceFileSummary{}
+				if err := decoder.DecodeElement(&summary, &start); err != nil {
+					return err
+				}
+				pkg.SourceFiles = append(pkg.SourceFiles, SourceFile{Name: summary.Name, Counters: summary.Counters})
+				return nil
+			}
+			sourceFile := SourceFile{}
+			if err := decoder.DecodeElement(&sourceFile, &start); err != nil {
+				return err
+			}
+			pkg.SourceFiles = append(pkg.SourceFiles, sourceFile)
+		default:
+			return decoder.Skip()
+		}
+		return nil
+	})
+	return pkg, err
+}
+
+// decodeChildren calls onStart for each child element of the current element until the end of the current
+// element is reached. onStart needs to consume the complete child element.
+func decodeChildren(decoder *xml.Decoder, onStart func(start xml.StartElement) error) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := onStart(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// attr returns the value of the attribute with the specified name or the empty string if there is no such attribute.
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJaCoCoMatchesUnmarshal(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/jacoco.xml")
+	assert.NoError(t, err)
+	expected, err := parseJaCoCo(data)
+	assert.NoError(t, err)
+
+	file, err := os.Open("testdata/jacoco.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	report, err := DecodeJaCoCo(file, DecodeOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, report)
+}
+
+func TestDecodeJaCoCoSkipLines(t *testing.T) {
+	file, err := os.Open("testdata/jacoco.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	report, err := DecodeJaCoCo(file, DecodeOptions{SkipLines: true})
+	assert.NoError(t, err)
+	assert.Len(t, report.Packages, 1)
+	assert.Len(t, report.Packages[0].Classes, 1)
+	assert.Len(t, report.Packages[0].SourceFiles, 1)
+	assert.Equal(t, "DemoApplication.java", report.Packages[0].SourceFiles[0].Name)
+	assert.Empty(t, report.Packages[0].SourceFiles[0].Lines)
+	assert.Len(t, report.Packages[0].SourceFiles[0].Counters, 5)
+}
+
+func TestDecodeJaCoCoSkipPackages(t *testing.T) {
+	file, err := os.Open("testdata/jacoco.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	report, err := DecodeJaCoCo(file, DecodeOptions{SkipPackages: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "demo", report.Name)
+	assert.Len(t, report.SessionInfo, 1)
+	assert.Empty(t, report.Packages)
+	assert.Equal(t, Counter{Type: CounterTypeLine, Missed: 3, Covered: 1}, report.Counters[1])
+}
+
+func TestDecodeJaCoCoInvalidReport(t *testing.T) {
+	var testCases = []string{
+		"<coverage></coverage>",
+		"<report name=\"demo\"><package name=\"foo\">",
+		"",
+	}
+
+	for _, testCase := range testCases {
+		_, err := DecodeJaCoCo(strings.NewReader(testCase), DecodeOptions{})
+		assert.Error(t, err, testCase)
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"github.com/cenkalti/backoff"
+	"strings"
 	"time"
 )
 
@@ -18,6 +19,18 @@ func Contains(list []string, s string) bool {
 	return false
 }
 
+// SplitList splits the specified comma separated list into its trimmed, non-empty elements.
+func SplitList(s string) []string {
+	var list []string
+	for _, element := range strings.Split(s, ",") {
+		element = strings.TrimSpace(element)
+		if element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
 // ApplyWithBackoff tries to apply the specified function using an exponential backoff algorithm.
 // If the function eventually succeed nil is returned, otherwise the error returned by f.
 func ApplyWithBackoff(f func() error) error {
@@ -26,3 +39,21 @@ func ApplyWithBackoff(f func() error) error {
 	exponentialBackOff.Reset()
 	return backoff.Retry(f, exponentialBackOff)
 }
+
+// ApplyWithBackoffIf tries to apply the specified function like ApplyWithBackoff, but only retries as long as retryable
+// returns true for the error returned by f. An error which is not retryable is returned immediately.
+func ApplyWithBackoffIf(f func() error, retryable func(error) bool) error {
+	var permanent error
+	err := ApplyWithBackoff(func() error {
+		err := f()
+		if err != nil && !retryable(err) {
+			permanent = err
+			return nil
+		}
+		return err
+	})
+	if permanent != nil {
+		return permanent
+	}
+	return err
+}
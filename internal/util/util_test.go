@@ -24,6 +24,11 @@ func TestContains(t *testing.T) {
 	}
 }
 
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, SplitList("a, b,,c "))
+	assert.Empty(t, SplitList(" "))
+}
+
 func TestApplyWithBackoffFailure(t *testing.T) {
 	origTimeout := timeout
 	defer func() {
@@ -62,3 +67,21 @@ func TestApplyWithBackoffSuccess(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 3, callCount)
 }
+
+func TestApplyWithBackoffIfNotRetryable(t *testing.T) {
+	permanent := errors.New("permanent")
+	var callCount = 0
+	f := func() error {
+		callCount++
+		if callCount == 2 {
+			return permanent
+		}
+		return errors.New("bang")
+	}
+	err := ApplyWithBackoffIf(f, func(err error) bool {
+		return err != permanent
+	})
+
+	assert.Equal(t, permanent, err)
+	assert.Equal(t, 2, callCount)
+}
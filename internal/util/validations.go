@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"strconv"
+	"strings"
 )
 
 // IsNotEmpty checks if value stored at given key is empty.
@@ -30,6 +31,15 @@ func IsInt(value interface{}, key string) error {
 	return nil
 }
 
+// IsPositiveInt checks if the value stored at a given key is an int greater than zero.
+func IsPositiveInt(value interface{}, key string) error {
+	i, err := strconv.Atoi(value.(string))
+	if err != nil || i <= 0 {
+		return errors.New(fmt.Sprintf("Value for %s needs to be a positive integer.", key))
+	}
+	return nil
+}
+
 // IsBool checks if value stored at a given key is a bool.
 func IsBool(value interface{}, key string) error {
 	_, err := strconv.ParseBool(value.(string))
@@ -38,3 +48,33 @@ func IsBool(value interface{}, key string) error {
 	}
 	return nil
 }
+
+// IsOneOf returns a validation which checks if the value stored at a given key is one of the specified values.
+func IsOneOf(values ...string) func(interface{}, string) error {
+	return func(value interface{}, key string) error {
+		s, ok := value.(string)
+		if !ok || !Contains(values, s) {
+			return errors.New(fmt.Sprintf("Value for %s needs to be one of [%s].", key, strings.Join(values, ", ")))
+		}
+		return nil
+	}
+}
+
+// IsListOf returns a validation which checks if the value stored at a given key is a comma separated list of the
+// specified values.
+func IsListOf(values ...string) func(interface{}, string) error {
+	return func(value interface{}, key string) error {
+		s, ok := value.(string)
+		if ok {
+			for _, element := range SplitList(s) {
+				if !Contains(values, element) {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			return errors.New(fmt.Sprintf("Value for %s needs to be a comma separated list of [%s].", key, strings.Join(values, ", ")))
+		}
+		return nil
+	}
+}
@@ -33,3 +33,78 @@ func Test_IsBool(t *testing.T) {
 		assert.Equal(t, testBool.errors, errors, fmt.Sprintf("Unexpected error for %s", testBool.value))
 	}
 }
+
+func Test_IsPositiveInt(t *testing.T) {
+	var testValues = []struct {
+		value  string
+		errors []string
+	}{
+		{"1", []string{}},
+		{"15", []string{}},
+		{"0", []string{"Value for FOO needs to be a positive integer."}},
+		{"-1", []string{"Value for FOO needs to be a positive integer."}},
+		{"foo", []string{"Value for FOO needs to be a positive integer."}},
+	}
+
+	for _, testValue := range testValues {
+		err := IsPositiveInt(testValue.value, "FOO")
+		var errors []string
+		if err == nil {
+			errors = []string{}
+		} else {
+			errors = strings.Split(err.Error(), "\n")
+		}
+
+		assert.Equal(t, testValue.errors, errors, fmt.Sprintf("Unexpected error for %s", testValue.value))
+	}
+}
+
+func Test_IsOneOf(t *testing.T) {
+	var testValues = []struct {
+		value  string
+		errors []string
+	}{
+		{"merged", []string{}},
+		{"module", []string{}},
+		{"snafu", []string{"Value for FOO needs to be one of [merged, module]."}},
+		{"", []string{"Value for FOO needs to be one of [merged, module]."}},
+	}
+
+	isOneOf := IsOneOf("merged", "module")
+	for _, testValue := range testValues {
+		err := isOneOf(testValue.value, "FOO")
+		var errors []string
+		if err == nil {
+			errors = []string{}
+		} else {
+			errors = strings.Split(err.Error(), "\n")
+		}
+
+		assert.Equal(t, testValue.errors, errors, fmt.Sprintf("Unexpected error for %s", testValue.value))
+	}
+}
+
+func Test_IsListOf(t *testing.T) {
+	var testValues = []struct {
+		value  string
+		errors []string
+	}{
+		{"Succeeded", []string{}},
+		{"Succeeded, Failed", []string{}},
+		{"", []string{}},
+		{"Succeeded,Running", []string{"Value for FOO needs to be a comma separated list of [Succeeded, Failed]."}},
+	}
+
+	isListOf := IsListOf("Succeeded", "Failed")
+	for _, testValue := range testValues {
+		err := isListOf(testValue.value, "FOO")
+		var errors []string
+		if err == nil {
+			errors = []string{}
+		} else {
+			errors = strings.Split(err.Error(), "\n")
+		}
+
+		assert.Equal(t, testValue.errors, errors, fmt.Sprintf("Unexpected error for %s", testValue.value))
+	}
+}